@@ -1,28 +1,46 @@
 package confbuilder
 
 import (
-	"encoding/json"
 	"fmt"
-	"log/slog"
-	"os"
-	"path/filepath"
+	"io"
 	"reflect"
-	"strconv"
 	"strings"
-	"time"
 
 	"github.com/go-playground/validator/v10"
 	"github.com/jinzhu/copier"
-	"github.com/joho/godotenv"
 )
 
 // Builder implements a generic builder pattern for creating configuration instances
 type Builder[T any] struct {
-	config    T
-	envPrefix string
-	envTag    string
-	envFiles  []string
-	filepath  *string
+	config     T
+	envPrefix  string
+	envTag     string
+	autoEnv    NamingStrategy
+	envFiles   []string
+	envSearch  envFileSearch
+	filepath   *string
+	fileFormat Format
+	files      []FileLayer
+	remote     *remoteSource
+	sources    []Source
+	profile    string
+	profileEnv string
+
+	flagsEnabled bool
+	flagArgs     []string
+	flagOutput   io.Writer
+
+	env           EnvSource
+	allowEmptyEnv bool
+	secretFiles   bool
+	resolvers     map[string]SecretResolver
+	decoders      decoders
+	strict        StrictMode
+
+	validate      *validator.Validate       // Reused across builds
+	validations   map[string]validator.Func // Custom rules, registered again on a replaced validator
+	validationErr error                     // First rule registration failure, reported by Build
+	messages      map[string]string         // Message templates by validation tag
 }
 
 // New returns a Builder with the provided default configuration and options
@@ -33,6 +51,15 @@ func New[T any](defaultConf T) *Builder[T] {
 		envTag:    "env", // Default tag
 		envFiles:  []string{},
 		filepath:  nil, // No file path by default
+		files:     []FileLayer{},
+
+		env:         OSEnv{},
+		secretFiles: true, // Docker and Kubernetes secret files are resolved by default
+		decoders:    defaultDecoders(),
+
+		validate:    newValidator(),
+		validations: map[string]validator.Func{},
+		messages:    map[string]string{},
 	}
 
 	return b
@@ -50,250 +77,356 @@ func (b *Builder[T]) EnvTag(tag string) *Builder[T] {
 	return b
 }
 
+// AutoEnv binds fields without env tag to the names derived by strategy from their path, e.g.
+// Database.MaxIdleConns becomes DATABASE_MAX_IDLE_CONNS with SnakeUpper. Explicit tags still
+// win and fields tagged "-" are excluded
+func (b *Builder[T]) AutoEnv(strategy NamingStrategy) *Builder[T] {
+	b.autoEnv = strategy
+	return b
+}
+
+// naming returns how fields are bound to env variables
+func (b *Builder[T]) naming() envNaming {
+	return envNaming{tag: b.envTag, auto: b.autoEnv, decoders: b.decoders}
+}
+
+// SecretFiles enables or disables resolving NAME_FILE variables, and file:// values of fields
+// tagged secret:"true", by reading the referenced file, enabled by default
+func (b *Builder[T]) SecretFiles(enabled bool) *Builder[T] {
+	b.secretFiles = enabled
+	return b
+}
+
+// Env sets the source of the env variables, the process environment by default. Variables
+// from .env files are layered below it
+func (b *Builder[T]) Env(source EnvSource) *Builder[T] {
+	b.env = source
+	return b
+}
+
+// AllowEmptyEnv makes variables set to an empty string clear their field, e.g. TAGS= resets a
+// slice to empty, instead of keeping the current value. Fields tagged `env:"NAME,allowempty"`
+// always behave this way
+func (b *Builder[T]) AllowEmptyEnv(allow bool) *Builder[T] {
+	b.allowEmptyEnv = allow
+	return b
+}
+
 // EnvFiles sets the environment files to load
 func (b *Builder[T]) EnvFiles(files ...string) *Builder[T] {
 	b.envFiles = files
 	return b
 }
 
-// LoadFile loads configuration from a JSON file
+// EnvFileRootMarkers stops the .env file search at the first directory holding one of markers,
+// e.g. go.mod or .git, so that files above the project are ignored
+func (b *Builder[T]) EnvFileRootMarkers(markers ...string) *Builder[T] {
+	b.envSearch.markers = markers
+	return b
+}
+
+// EnvFileSearchRoot stops the .env file search at dir, files in its parents are ignored. No
+// .env file is loaded when the working directory is outside of dir
+func (b *Builder[T]) EnvFileSearchRoot(dir string) *Builder[T] {
+	b.envSearch.root = dir
+	return b
+}
+
+// EnvFileOrder sets which .env file wins when several set a variable, the nearest by default
+func (b *Builder[T]) EnvFileOrder(order EnvFileOrder) *Builder[T] {
+	b.envSearch.order = order
+	return b
+}
+
+// EnvPrecedence sets whether .env files override the env source, they never do by default
+func (b *Builder[T]) EnvPrecedence(precedence EnvPrecedence) *Builder[T] {
+	b.envSearch.precedence = precedence
+	return b
+}
+
+// File sets the configuration file to load, its format is detected from the extension
 func (b *Builder[T]) File(filepath *string) *Builder[T] {
 	b.filepath = filepath
 	return b
 }
 
-// Build validates and returns the final configuration
-func (b *Builder[T]) Build() (T, error) {
-	var config T
+// FileFormat forces the format of the configuration file instead of detecting it
+func (b *Builder[T]) FileFormat(format Format) *Builder[T] {
+	b.fileFormat = format
+	return b
+}
 
-	// Check if the source config is nil (for pointer types)
-	sourceValue := reflect.ValueOf(b.config)
-	if sourceValue.Kind() == reflect.Ptr && sourceValue.IsNil() {
-		return config, fmt.Errorf("cannot load environment variables into nil pointer")
+// Files sets additional configuration files deep merged in order after the File one, each
+// overriding only the keys it sets, map entries included
+func (b *Builder[T]) Files(layers ...FileLayer) *Builder[T] {
+	b.files = layers
+	return b
+}
+
+// fileLayers returns the File and Files configuration layers in merge order
+func (b *Builder[T]) fileLayers() []FileLayer {
+	layers := make([]FileLayer, 0, len(b.files)+1)
+	if b.filepath != nil && *b.filepath != "" {
+		layers = append(layers, FileLayer{Path: *b.filepath, Format: b.fileFormat})
 	}
+	return append(layers, b.files...)
+}
 
-	// Use reflection to determine if T is a pointer type
-	configType := reflect.TypeOf(config)
-	isPointer := configType.Kind() == reflect.Ptr
+// Sources replaces the layers run by Build, in order, after the defaults and before validation,
+// e.g. to reorder the built-in layers or to add custom ones
+func (b *Builder[T]) Sources(sources ...Source) *Builder[T] {
+	b.sources = sources
+	return b
+}
 
-	// Allocate memory for pointer types
-	if isPointer {
-		configValue := reflect.New(configType.Elem())
-		config = configValue.Interface().(T)
+// DefaultSources returns the layers run by Build when Sources is not set: the configuration
+// files, the remote document, the .env files and the env variables, the .env files winning over
+// the env source with EnvFileWins, then the command-line flags when enabled
+func (b *Builder[T]) DefaultSources() []Source {
+	sources := []Source{FromFiles()}
+	if b.remote != nil {
+		sources = append(sources, b.remote)
 	}
-
-	// Determine the target for operations that need a pointer
-	var target any
-	if isPointer {
-		target = config
+	if b.envSearch.precedence == EnvFileWins {
+		sources = append(sources, FromEnv(), FromDotenv())
 	} else {
-		target = &config
+		sources = append(sources, FromDotenv(), FromEnv())
+	}
+	if b.flagsEnabled {
+		sources = append(sources, FromFlags(b.flagArgs))
 	}
+	return sources
+}
 
-	// Clone the config to avoid modifying the original instance
-	if err := copier.Copy(target, b.config); err != nil {
-		return config, fmt.Errorf("failed to clone config: %w", err)
+// pipeline returns the sources run by Build
+func (b *Builder[T]) pipeline() []Source {
+	if b.sources != nil {
+		return b.sources
 	}
+	return b.DefaultSources()
+}
 
-	// Load from file if specified
-	if b.filepath != nil && *b.filepath != "" {
-		data, err := os.ReadFile(*b.filepath)
-		if err != nil {
-			return config, fmt.Errorf("failed to read config file: %w", err)
-		}
+// newLayer returns the state shared by the sources of one build
+func (b *Builder[T]) newLayer(target any, profile string, report *Report) *Layer {
+	search := b.envSearch
+	search.names = b.envFiles
+	l := &Layer{
+		target:      target,
+		profile:     profile,
+		report:      report,
+		errs:        &ConfigError{},
+		prefix:      b.envPrefix,
+		naming:      b.naming(),
+		decoders:    b.decoders,
+		env:         b.env,
+		dotenv:      MapEnv{},
+		allowEmpty:  b.allowEmptyEnv,
+		secretFiles: b.secretFiles,
+		fileLayers:  b.fileLayers(),
+		envSearch:   search,
+		flagOutput:  b.flagOutput,
+	}
+	l.resolvers = l.bindResolvers(b.resolvers)
+	return l
+}
 
-		if err := json.Unmarshal(data, target); err != nil {
-			return config, fmt.Errorf("failed to parse config file: %w", err)
+// Build validates and returns the final configuration
+func (b *Builder[T]) Build() (T, error) {
+	config, _, err := b.BuildWithReport()
+	return config, err
+}
+
+// BuildWithReport validates and returns the final configuration together with a report
+// recording where every value came from
+func (b *Builder[T]) BuildWithReport() (T, *Report, error) {
+	report := newReport()
+
+	config, target, err := cloneConfig(b.config)
+	if err != nil {
+		return *config, report, err
+	}
+
+	profile, err := b.activeProfile()
+	if err != nil {
+		return *config, report, err
+	}
+	report.Profile = profile
+
+	// Fill zero fields from their default tags and expose the profile to the configuration
+	if err := applyDefaults(target, b.naming(), b.decoders); err != nil {
+		return *config, report, fmt.Errorf("failed to apply default values: %w", err)
+	}
+	if err := setProfile(target, b.naming(), profile); err != nil {
+		return *config, report, err
+	}
+
+	// Record every field as a default before the other sources override them
+	if err := recordDefaults(target, b.naming(), report.Provenance); err != nil {
+		return *config, report, err
+	}
+
+	// Run the sources in order, every one overriding the previous ones
+	layer := b.newLayer(target, profile, report)
+	for _, source := range b.pipeline() {
+		if err := source.Load(layer); err != nil {
+			return *config, report, err
 		}
 	}
+	cfgErr := layer.errs
 
-	// Load environment files
-	if err := loadEnvFromAncestors(b.envFiles...); err != nil {
-		return config, fmt.Errorf("failed to load environment variables: %w", err)
+	// Report config file keys and env variables matching no field
+	if err := checkStrict(b.strict, target, b.naming(), layer.files, layer.loaders, b.reservedEnv(), cfgErr); err != nil {
+		return *config, report, err
 	}
 
-	// Load environment variables into struct
-	if err := loadEnvToStruct(target, b.envPrefix, b.envTag, ""); err != nil {
-		return config, fmt.Errorf("failed to override configuration from environment: %w", err)
+	// Validate the configuration with the validate tags, then with the Validate methods
+	if b.validationErr != nil {
+		return *config, report, b.validationErr
 	}
+	if err := b.validate.Struct(target); err != nil {
+		if err := addValidationErrors(cfgErr, err, target, b.envPrefix, b.naming(), b.messages, report.Provenance); err != nil {
+			return *config, report, fmt.Errorf("invalid configuration: %w", err)
+		}
+	}
+	runValidateHooks(reflect.ValueOf(target), "", func(path string, err error) {
+		addHookError(cfgErr, err, path, report.Provenance)
+	})
 
-	// Validate the configuration
-	v := validator.New()
-	if err := v.Struct(target); err != nil {
-		return config, fmt.Errorf("invalid configuration: %w", err)
+	// Report every parse and validation problem at once
+	if err := cfgErr.errOrNil(); err != nil {
+		return *config, report, err
 	}
 
-	return config, nil
+	return *config, report, nil
 }
 
-// loadEnvToStruct loads environment variables into struct fields and nested structs based on tags
-func loadEnvToStruct(target any, prefix, tag, parentEnvPath string) error {
-	v := reflect.ValueOf(target)
+// cloneConfig deep copies src, returning a pointer to the copy and a pointer to the struct
+// usable as a decode target. For pointer types both point to the same struct
+func cloneConfig[T any](src T) (*T, any, error) {
+	config := new(T)
 
-	// Dereference all pointer levels to get to the actual value
-	for v.Kind() == reflect.Ptr {
-		if v.IsNil() {
-			return fmt.Errorf("cannot load environment variables into nil pointer")
-		}
-		v = v.Elem()
+	// Check if the source config is nil (for pointer types)
+	sourceValue := reflect.ValueOf(src)
+	if sourceValue.Kind() == reflect.Ptr && sourceValue.IsNil() {
+		return config, nil, fmt.Errorf("cannot load environment variables into nil pointer")
 	}
 
-	t := v.Type()
+	// Allocate memory for pointer types
+	var target any = config
+	if configType := reflect.TypeOf(*config); configType.Kind() == reflect.Ptr {
+		*config = reflect.New(configType.Elem()).Interface().(T)
+		target = *config
+	}
 
-	for i := 0; i < t.NumField(); i++ {
-		field := t.Field(i)
-		fieldValue := v.Field(i)
+	// Deep clone the config so that slices and maps are not shared with the original instance
+	if err := copier.CopyWithOption(target, src, copier.Option{DeepCopy: true}); err != nil {
+		return config, target, fmt.Errorf("failed to clone config: %w", err)
+	}
+	return config, target, nil
+}
 
-		// Skip unexported fields
-		if !fieldValue.CanSet() {
-			continue
-		}
+// envLoader loads environment variables into configuration structs
+type envLoader struct {
+	prefix      string
+	naming      envNaming
+	env         EnvSource
+	source      SourceKind // Recorded for variables not provided by a .env file
+	secretFiles bool       // Resolve NAME_FILE variables and file:// values of secret fields
+	resolvers   map[string]SecretResolver
+	decoders    decoders
+	envFileKeys map[string]string // Variables set by .env files mapped to the file that provided them
+	provenance  Provenance
+	errs        *ConfigError // Collects invalid values instead of stopping at the first one
+	pathPrefix  string       // Field path of the collection element being loaded, e.g. Upstreams[1]
+	allowEmpty  bool         // Set and empty variables clear their field
+}
 
-		// Get env tag or skip if not present
-		// Check if field is a struct that needs recursive processing
-		if fieldValue.Kind() == reflect.Struct {
-			// Skip time.Duration which is technically a struct but should be treated as primitive
-			if field.Type != reflect.TypeOf(time.Duration(0)) {
-				// Get the env tag for this struct field to build the path
-				fieldEnvTag, hasEnvTag := field.Tag.Lookup(tag)
-				var newEnvPath string
-				if hasEnvTag && fieldEnvTag != "" {
-					if parentEnvPath == "" {
-						newEnvPath = fieldEnvTag
-					} else {
-						newEnvPath = parentEnvPath + "_" + fieldEnvTag
-					}
-				} else {
-					newEnvPath = parentEnvPath
-				}
-
-				if err := loadEnvToStruct(fieldValue.Addr().Interface(), prefix, tag, newEnvPath); err != nil {
-					return fmt.Errorf("error loading sub config field %s: %w", field.Name, err)
-				}
-			}
+// loadEnvToStruct loads environment variables into struct fields and nested structs based on tags,
+// invalid values are collected in l.errs so that every problem is reported at once
+func (l *envLoader) loadEnvToStruct(target any) error {
+	return walkFields(target, l.naming, func(f fieldInfo) error {
+		if f.envKey == "" {
+			return nil
 		}
+		f.path = joinPath(l.pathPrefix, f.path)
 
-		envVar, ok := field.Tag.Lookup(tag)
-		if !ok || envVar == "" {
-			continue
+		// Slices and maps of structs are filled from indexed variables, e.g. UPSTREAMS_0_URL
+		if isStructCollection(f.value.Type()) {
+			return l.loadIndexed(f)
 		}
 
-		// Build the full environment variable name by concatenating parent path with current field env tag
-		var fullEnvVar string
-		if parentEnvPath == "" {
-			fullEnvVar = envVar
-		} else {
-			fullEnvVar = parentEnvPath + "_" + envVar
+		// Get value from environment, unset and empty variables keep the current value unless
+		// empty values are allowed
+		envValue, origin, set, err := l.lookup(l.prefix+f.envKey, isSecret(f.field))
+		if err != nil {
+			l.fail(f, origin, envValue, err)
+			return nil
 		}
-
-		// Get value from environment or skip if empty
-		envValue := os.Getenv(prefix + fullEnvVar)
 		if envValue == "" {
-			continue
-		}
-
-		// Set field value based on type
-		switch fieldValue.Kind() {
-		case reflect.String:
-			fieldValue.SetString(envValue)
-
-		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-			if field.Type == reflect.TypeOf(time.Duration(0)) {
-				// Handle time.Duration
-				duration, err := time.ParseDuration(envValue)
-				if err != nil {
-					return fmt.Errorf("invalid duration value for %s: %w", envVar, err)
-				}
-				fieldValue.SetInt(int64(duration))
-			} else if field.Type == reflect.TypeOf(slog.Level(0)) {
-				// Handle slog.Level - support both numeric and string values
-				var level slog.Level
-				if err := level.UnmarshalText([]byte(strings.ToUpper(envValue))); err != nil {
-					return fmt.Errorf("invalid slog level value for %s: %s", envVar, envValue)
-				}
-				fieldValue.SetInt(int64(level))
-			} else {
-				// Handle regular integers
-				val, err := strconv.ParseInt(envValue, 10, 64)
-				if err != nil {
-					return fmt.Errorf("invalid integer value for %s: %w", envVar, err)
-				}
-				fieldValue.SetInt(val)
+			if set && (l.allowEmpty || l.naming.option(f.field, "allowempty")) {
+				clearValue(f.value)
+				f.ensure()
+				l.provenance[f.path] = origin
 			}
+			return nil
+		}
 
-		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
-			val, err := strconv.ParseUint(envValue, 10, 64)
-			if err != nil {
-				return fmt.Errorf("invalid unsigned integer value for %s: %w", envVar, err)
-			}
-			fieldValue.SetUint(val)
-
-		case reflect.Float32, reflect.Float64:
-			val, err := strconv.ParseFloat(envValue, 64)
-			if err != nil {
-				return fmt.Errorf("invalid float value for %s: %w", envVar, err)
-			}
-			fieldValue.SetFloat(val)
-
-		case reflect.Bool:
-			val, err := strconv.ParseBool(envValue)
-			if err != nil {
-				return fmt.Errorf("invalid boolean value for %s: %w", envVar, err)
-			}
-			fieldValue.SetBool(val)
-
-		case reflect.Slice:
-			// Handle []string specifically. Add other slice types if needed.
-			if fieldValue.Type().Elem().Kind() == reflect.String {
-				parts := strings.Split(envValue, ",")
-				// Trim spaces from each part
-				for i, p := range parts {
-					parts[i] = strings.TrimSpace(p)
-				}
-				fieldValue.Set(reflect.ValueOf(parts))
-			}
+		// Expand ${scheme:ref} placeholders before type conversion
+		resolved, err := interpolate(envValue, l.resolvers)
+		if err != nil {
+			l.fail(f, origin, envValue, fmt.Errorf("failed to resolve %s: %w", origin.Key, err))
+			return nil
 		}
-	}
 
-	return nil
+		if err := l.decoders.setFieldValue(f.value, origin.Key, resolved); err != nil {
+			l.fail(f, origin, resolved, err)
+			return nil
+		}
+		f.ensure()
+		l.provenance[f.path] = origin
+		return nil
+	})
 }
 
-// loadEnvFromAncestors searches for .env files from the current directory up to the root
-func loadEnvFromAncestors(filesToTry ...string) error {
-	// Get current working directory
-	currentDir, err := os.Getwd()
-	if err != nil {
-		return err
-	}
+// fail records an invalid env value for the field
+func (l *envLoader) fail(f fieldInfo, origin Origin, raw string, err error) {
+	l.errs.add(FieldError{
+		Path:    f.path,
+		EnvVar:  origin.Key,
+		Source:  origin.Source,
+		Value:   displayValue(f, raw),
+		Message: err.Error(),
+		Err:     err,
+	})
+}
 
-	// Track if we found any env files
-	found := false
-
-	// Start from current directory and move up
-	dir := currentDir
-	for {
-		for _, fileName := range filesToTry {
-			envPath := filepath.Join(dir, fileName)
-			if _, err := os.Stat(envPath); err == nil {
-				// File exists, load it
-				if err := godotenv.Load(envPath); err == nil {
-					slog.Info("Loading .env file", "file", envPath)
-					found = true
-				}
-			}
+// lookup returns the value of the env variable name, resolving secret file references,
+// together with the origin of the value and whether the variable is set. file:// values are
+// only read for secret fields, other fields may legitimately hold file URLs
+func (l *envLoader) lookup(name string, secret bool) (string, Origin, bool, error) {
+	value, set := l.env.Lookup(name)
+	origin := l.origin(name)
+
+	if l.secretFiles {
+		if path, ok := strings.CutPrefix(value, fileScheme); ok && secret {
+			value, origin, err := readSecretFile(name, path, origin)
+			return value, origin, true, err
 		}
-
-		// Stop if we've reached the root directory
-		parentDir := filepath.Dir(dir)
-		if parentDir == dir {
-			break // We've reached the root
+		if value == "" {
+			if path, _ := l.env.Lookup(name + fileSuffix); path != "" {
+				value, origin, err := readSecretFile(name+fileSuffix, path, l.origin(name+fileSuffix))
+				return value, origin, true, err
+			}
 		}
-		dir = parentDir
 	}
 
-	if !found {
-		slog.Info("No .env files found in ancestor directories")
-	}
+	return value, origin, set, nil
+}
 
-	return nil
+// origin returns where the env variable name was set, telling .env files apart from other sources
+func (l *envLoader) origin(name string) Origin {
+	if file, ok := l.envFileKeys[name]; ok {
+		return Origin{Source: SourceEnvFile, Key: name, File: file}
+	}
+	return Origin{Source: l.source, Key: name}
 }
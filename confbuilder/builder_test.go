@@ -579,6 +579,24 @@ func TestGenericBuilder_ConfigCloning(t *testing.T) {
 	assert.Equal(t, originalDBHost, originalCfg.Database.Host)
 }
 
+func TestGenericBuilder_ValueType(t *testing.T) {
+	dir := t.TempDir()
+	configPath := writeFile(t, dir, "config.json", `{"app_name": "from-file"}`)
+	setEnvVars(t, map[string]string{"VAL_DB_HOST": "db.example.com"})
+
+	original := *newTestConfig()
+	cfg, err := New(original).
+		EnvPrefix("VAL_").
+		File(&configPath).
+		Flags([]string{"--port", "9090"}).
+		Build()
+	require.NoError(t, err)
+	assert.Equal(t, "from-file", cfg.AppName)
+	assert.Equal(t, "db.example.com", cfg.Database.Host)
+	assert.Equal(t, 9090, cfg.Port)
+	assert.Equal(t, 8080, original.Port)
+}
+
 func TestGenericBuilder_EmptyEnvValues(t *testing.T) {
 	setEnvVars(t, map[string]string{
 		"TEST_APP_NAME":    "",
@@ -597,6 +615,67 @@ func TestGenericBuilder_EmptyEnvValues(t *testing.T) {
 	assert.Equal(t, 7070, cfg.Port)
 }
 
+type EmptyEnvConfig struct {
+	Name   string            `env:"NAME" default:"app"`
+	Port   int               `env:"PORT" default:"8080"`
+	Tags   []string          `env:"TAGS" default:"a,b"`
+	Labels map[string]string `env:"LABELS" default:"team:core"`
+	Note   string            `env:"NOTE,allowempty" default:"hello"`
+	Hosts  []string          `env:",allowempty" default:"x"`
+}
+
+func TestGenericBuilder_AllowEmptyEnv(t *testing.T) {
+	tests := []struct {
+		name    string
+		allow   bool
+		env     MapEnv
+		expect  EmptyEnvConfig
+		cleared []string
+	}{
+		{
+			name:   "Unset keeps defaults",
+			allow:  true,
+			env:    MapEnv{},
+			expect: EmptyEnvConfig{Name: "app", Port: 8080, Tags: []string{"a", "b"}, Labels: map[string]string{"team": "core"}, Note: "hello", Hosts: []string{"x"}},
+		},
+		{
+			name:    "Empty keeps defaults unless allowed per field",
+			env:     MapEnv{"APP_NAME": "", "APP_TAGS": "", "APP_NOTE": ""},
+			expect:  EmptyEnvConfig{Name: "app", Port: 8080, Tags: []string{"a", "b"}, Labels: map[string]string{"team": "core"}, Note: "", Hosts: []string{"x"}},
+			cleared: []string{"Note"},
+		},
+		{
+			name:    "Empty clears fields when allowed",
+			allow:   true,
+			env:     MapEnv{"APP_NAME": "", "APP_PORT": "", "APP_TAGS": "", "APP_LABELS": ""},
+			expect:  EmptyEnvConfig{Name: "", Port: 0, Tags: []string{}, Labels: map[string]string{}, Note: "hello", Hosts: []string{"x"}},
+			cleared: []string{"Name", "Port", "Tags", "Labels"},
+		},
+		{
+			name:    "Slice reset with auto naming",
+			env:     MapEnv{"APP_HOSTS": ""},
+			expect:  EmptyEnvConfig{Name: "app", Port: 8080, Tags: []string{"a", "b"}, Labels: map[string]string{"team": "core"}, Note: "hello", Hosts: []string{}},
+			cleared: []string{"Hosts"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, report, err := New(&EmptyEnvConfig{}).
+				EnvPrefix("APP_").
+				AutoEnv(SnakeUpper).
+				Env(tt.env).
+				AllowEmptyEnv(tt.allow).
+				BuildWithReport()
+			require.NoError(t, err)
+			assert.Equal(t, tt.expect, *cfg)
+			for _, path := range tt.cleared {
+				assert.Equal(t, SourceEnv, report.Provenance[path].Source, path)
+			}
+		})
+	}
+}
+
 func TestGenericBuilder_FieldsWithoutEnvTags(t *testing.T) {
 	setEnvVars(t, map[string]string{
 		"TEST_APP_NAME":    "new-name",
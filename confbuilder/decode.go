@@ -0,0 +1,387 @@
+package confbuilder
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	durationType        = reflect.TypeOf(time.Duration(0))
+	levelType           = reflect.TypeOf(slog.Level(0))
+	urlType             = reflect.TypeOf(url.URL{})
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	textMarshalerType   = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	jsonUnmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+	stringerType        = reflect.TypeOf((*fmt.Stringer)(nil)).Elem()
+)
+
+// DecodeFunc converts a raw string value into a value of the type it is registered for
+type DecodeFunc func(raw string) (any, error)
+
+// decoders maps types to the functions decoding them from strings, they take precedence over
+// the built-in conversions for env variables, flags and string values of config files
+type decoders map[reflect.Type]DecodeFunc
+
+// defaultDecoders returns the decoders registered on every builder
+func defaultDecoders() decoders {
+	return decoders{
+		durationType: func(raw string) (any, error) {
+			return time.ParseDuration(raw)
+		},
+		levelType: func(raw string) (any, error) {
+			// Support both numeric and case-insensitive string values
+			var level slog.Level
+			err := level.UnmarshalText([]byte(strings.ToUpper(raw)))
+			return level, err
+		},
+		urlType: func(raw string) (any, error) {
+			u, err := url.Parse(raw)
+			if err != nil {
+				return nil, err
+			}
+			return *u, nil
+		},
+	}
+}
+
+// Decoder registers fn to decode string values into fields of type t, replacing any built-in
+// conversion for that type
+func (b *Builder[T]) Decoder(t reflect.Type, fn DecodeFunc) *Builder[T] {
+	b.decoders[t] = fn
+	return b
+}
+
+// RegisterDecoder registers a typed decoder for fields of type V, e.g.
+//
+//	confbuilder.RegisterDecoder(b, uuid.Parse)
+func RegisterDecoder[V, T any](b *Builder[T], fn func(raw string) (V, error)) *Builder[T] {
+	return b.Decoder(reflect.TypeOf((*V)(nil)).Elem(), func(raw string) (any, error) {
+		return fn(raw)
+	})
+}
+
+// typeLabel names a type in decoding errors
+func typeLabel(t reflect.Type) string {
+	switch t {
+	case durationType:
+		return "duration"
+	case levelType:
+		return "slog level"
+	case urlType:
+		return "URL"
+	}
+	return t.String()
+}
+
+// isLeafType reports whether values of struct type t are decoded as a whole rather than
+// walked field by field, e.g. time.Time, url.URL or any TextUnmarshaler
+func isLeafType(t reflect.Type) bool {
+	if t == urlType {
+		return true
+	}
+	ptr := reflect.PointerTo(t)
+	return ptr.Implements(textUnmarshalerType) || ptr.Implements(jsonUnmarshalerType)
+}
+
+// setFieldValue parses raw according to the field type and stores it, name identifies the
+// value origin in error messages
+func (d decoders) setFieldValue(fieldValue reflect.Value, name, raw string) error {
+	t := fieldValue.Type()
+
+	if fn, ok := d[t]; ok {
+		return setDecoded(fieldValue, name, raw, fn)
+	}
+
+	// Pointers get a freshly allocated value so that defaults shared with other configs
+	// are never written through
+	if t.Kind() == reflect.Ptr {
+		elem := reflect.New(t.Elem())
+		if err := d.setFieldValue(elem.Elem(), name, raw); err != nil {
+			return err
+		}
+		fieldValue.Set(elem)
+		return nil
+	}
+
+	// Types knowing how to decode themselves, e.g. time.Time, net.IP or netip.Addr
+	ptr := reflect.PointerTo(t)
+	if ptr.Implements(textUnmarshalerType) {
+		elem := reflect.New(t)
+		if err := elem.Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(raw)); err != nil {
+			return fmt.Errorf("invalid %s value for %s: %w", t, name, err)
+		}
+		fieldValue.Set(elem.Elem())
+		return nil
+	}
+	if ptr.Implements(jsonUnmarshalerType) {
+		return setJSONValue(fieldValue, name, raw)
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		fieldValue.SetString(raw)
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		val, err := strconv.ParseInt(raw, 10, t.Bits())
+		if err != nil {
+			return fmt.Errorf("invalid integer value for %s: %w", name, err)
+		}
+		fieldValue.SetInt(val)
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		val, err := strconv.ParseUint(raw, 10, t.Bits())
+		if err != nil {
+			return fmt.Errorf("invalid unsigned integer value for %s: %w", name, err)
+		}
+		fieldValue.SetUint(val)
+
+	case reflect.Float32, reflect.Float64:
+		val, err := strconv.ParseFloat(raw, t.Bits())
+		if err != nil {
+			return fmt.Errorf("invalid float value for %s: %w", name, err)
+		}
+		fieldValue.SetFloat(val)
+
+	case reflect.Complex64, reflect.Complex128:
+		val, err := strconv.ParseComplex(raw, t.Bits())
+		if err != nil {
+			return fmt.Errorf("invalid complex value for %s: %w", name, err)
+		}
+		fieldValue.SetComplex(val)
+
+	case reflect.Bool:
+		val, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fmt.Errorf("invalid boolean value for %s: %w", name, err)
+		}
+		fieldValue.SetBool(val)
+
+	case reflect.Slice:
+		// Byte slices take the raw value as is
+		if t.Elem().Kind() == reflect.Uint8 {
+			fieldValue.SetBytes([]byte(raw))
+			return nil
+		}
+		return d.setSliceValue(fieldValue, name, raw)
+
+	case reflect.Map:
+		return d.setMapValue(fieldValue, name, raw)
+
+	default:
+		return fmt.Errorf("unsupported type %s for %s", t, name)
+	}
+
+	return nil
+}
+
+// clearValue sets a field to its empty value, slices and maps become empty rather than nil so
+// that they are distinguishable from unset ones
+func clearValue(fieldValue reflect.Value) {
+	switch fieldValue.Kind() {
+	case reflect.Slice:
+		fieldValue.Set(reflect.MakeSlice(fieldValue.Type(), 0, 0))
+	case reflect.Map:
+		fieldValue.Set(reflect.MakeMap(fieldValue.Type()))
+	default:
+		fieldValue.Set(reflect.Zero(fieldValue.Type()))
+	}
+}
+
+// setSliceValue decodes a comma separated list, each item according to the element type
+func (d decoders) setSliceValue(fieldValue reflect.Value, name, raw string) error {
+	parts := splitList(raw)
+	slice := reflect.MakeSlice(fieldValue.Type(), len(parts), len(parts))
+	for i, part := range parts {
+		if err := d.setFieldValue(slice.Index(i), fmt.Sprintf("%s[%d]", name, i), part); err != nil {
+			return err
+		}
+	}
+	fieldValue.Set(slice)
+	return nil
+}
+
+// setMapValue decodes a comma separated list of key:value pairs, e.g. "a:1,b:2"
+func (d decoders) setMapValue(fieldValue reflect.Value, name, raw string) error {
+	t := fieldValue.Type()
+	m := reflect.MakeMap(t)
+	for _, pair := range splitList(raw) {
+		rawKey, rawValue, ok := strings.Cut(pair, ":")
+		if !ok {
+			return fmt.Errorf("invalid map entry %q for %s: expected key:value", pair, name)
+		}
+
+		key := reflect.New(t.Key()).Elem()
+		if err := d.setFieldValue(key, name, strings.TrimSpace(rawKey)); err != nil {
+			return err
+		}
+		value := reflect.New(t.Elem()).Elem()
+		if err := d.setFieldValue(value, fmt.Sprintf("%s[%s]", name, rawKey), strings.TrimSpace(rawValue)); err != nil {
+			return err
+		}
+		m.SetMapIndex(key, value)
+	}
+	fieldValue.Set(m)
+	return nil
+}
+
+// setDecoded stores the result of a registered decoder
+func setDecoded(fieldValue reflect.Value, name, raw string, fn DecodeFunc) error {
+	t := fieldValue.Type()
+	decoded, err := fn(raw)
+	if err != nil {
+		return fmt.Errorf("invalid %s value for %s: %w", typeLabel(t), name, err)
+	}
+	if decoded == nil {
+		fieldValue.Set(reflect.Zero(t))
+		return nil
+	}
+
+	v := reflect.ValueOf(decoded)
+	if !v.Type().ConvertibleTo(t) {
+		return fmt.Errorf("decoder for %s returned %s for %s", t, v.Type(), name)
+	}
+	fieldValue.Set(v.Convert(t))
+	return nil
+}
+
+// decodeTree decodes the string values of a config file tree whose field type has a registered
+// decoder, e.g. "30s" for a time.Duration, and removes them from the tree so that the JSON
+// decoding of the remaining values leaves them untouched
+func (d decoders) decodeTree(target any, tree any, naming envNaming) error {
+	if tree == nil {
+		return nil
+	}
+	return walkFields(target, naming, func(f fieldInfo) error {
+		if f.jsonPath == nil {
+			return nil
+		}
+		t := f.value.Type()
+		if _, ok := d[t]; !ok {
+			if t.Kind() != reflect.Ptr {
+				return nil
+			}
+			if _, ok := d[t.Elem()]; !ok {
+				return nil
+			}
+		}
+
+		parent, key, ok := treeLeaf(tree, f.jsonPath)
+		if !ok {
+			return nil
+		}
+		raw, ok := parent[key].(string)
+		if !ok {
+			return nil
+		}
+		if err := d.setFieldValue(f.value, strings.Join(f.jsonPath, "."), raw); err != nil {
+			return err
+		}
+		f.ensure()
+		delete(parent, key)
+		return nil
+	})
+}
+
+// treeLeaf returns the map holding the value located by keys, matched case-insensitively
+// like encoding/json does, together with the actual key
+func treeLeaf(tree any, keys []string) (map[string]any, string, bool) {
+	for i, key := range keys {
+		m, ok := tree.(map[string]any)
+		if !ok {
+			return nil, "", false
+		}
+		found := ""
+		for k := range m {
+			if strings.EqualFold(k, key) {
+				found = k
+				break
+			}
+		}
+		if found == "" {
+			return nil, "", false
+		}
+		if i == len(keys)-1 {
+			return m, found, true
+		}
+		tree = m[found]
+	}
+	return nil, "", false
+}
+
+// setJSONValue decodes raw with the type's UnmarshalJSON, trying it as a JSON document first
+// and as a JSON string second
+func setJSONValue(fieldValue reflect.Value, name, raw string) error {
+	elem := reflect.New(fieldValue.Type())
+	unmarshaler := elem.Interface().(json.Unmarshaler)
+	if err := unmarshaler.UnmarshalJSON([]byte(raw)); err != nil {
+		quoted, _ := json.Marshal(raw)
+		if quotedErr := unmarshaler.UnmarshalJSON(quoted); quotedErr != nil {
+			return fmt.Errorf("invalid %s value for %s: %w", fieldValue.Type(), name, err)
+		}
+	}
+	fieldValue.Set(elem.Elem())
+	return nil
+}
+
+// splitList splits a comma separated list and trims spaces around each item
+func splitList(raw string) []string {
+	parts := strings.Split(raw, ",")
+	for i, p := range parts {
+		parts[i] = strings.TrimSpace(p)
+	}
+	return parts
+}
+
+// formatValue renders a field value in the same textual form accepted by setFieldValue
+func formatValue(fieldValue reflect.Value) string {
+	t := fieldValue.Type()
+
+	switch {
+	case t.Kind() == reflect.Ptr || t.Kind() == reflect.Interface:
+		if fieldValue.IsNil() {
+			return ""
+		}
+		return formatValue(fieldValue.Elem())
+	case t == durationType || t == levelType:
+		return fieldValue.Interface().(fmt.Stringer).String()
+	case t == urlType:
+		u := fieldValue.Interface().(url.URL)
+		return u.String()
+	case t.Implements(textMarshalerType):
+		if text, err := fieldValue.Interface().(encoding.TextMarshaler).MarshalText(); err == nil {
+			return string(text)
+		}
+	case t.Implements(stringerType) && t.Kind() == reflect.Struct:
+		return fieldValue.Interface().(fmt.Stringer).String()
+	}
+
+	switch t.Kind() {
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 && t.Kind() == reflect.Slice {
+			return string(fieldValue.Bytes())
+		}
+		parts := make([]string, fieldValue.Len())
+		for i := range parts {
+			parts[i] = formatValue(fieldValue.Index(i))
+		}
+		return strings.Join(parts, ",")
+
+	case reflect.Map:
+		parts := make([]string, 0, fieldValue.Len())
+		iter := fieldValue.MapRange()
+		for iter.Next() {
+			parts = append(parts, formatValue(iter.Key())+":"+formatValue(iter.Value()))
+		}
+		sort.Strings(parts)
+		return strings.Join(parts, ",")
+	}
+	return fmt.Sprint(fieldValue.Interface())
+}
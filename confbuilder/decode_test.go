@@ -0,0 +1,303 @@
+package confbuilder
+
+import (
+	"encoding/json"
+	"net"
+	"net/url"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// jsonPair decodes itself with UnmarshalJSON only
+type jsonPair struct {
+	Left, Right string
+}
+
+func (p *jsonPair) UnmarshalJSON(data []byte) error {
+	var raw [2]string
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	p.Left, p.Right = raw[0], raw[1]
+	return nil
+}
+
+type DecodeConfig struct {
+	Ports     []int             `env:"PORTS"`
+	Timeouts  []time.Duration   `env:"TIMEOUTS"`
+	Labels    map[string]string `env:"LABELS"`
+	Weights   map[string]int    `env:"WEIGHTS"`
+	MaxConns  *int              `env:"MAX_CONNS"`
+	Debug     *bool             `env:"DEBUG"`
+	Endpoint  url.URL           `env:"ENDPOINT"`
+	Proxy     *url.URL          `env:"PROXY"`
+	BindIP    net.IP            `env:"BIND_IP"`
+	StartAt   time.Time         `env:"START_AT"`
+	Pair      jsonPair          `env:"PAIR"`
+	Ratio     float32           `env:"RATIO"`
+	Cache     *CacheConfig      `env:"CACHE"`
+	Unused    *CacheConfig      `env:"UNUSED"`
+	Callbacks chan int          `env:"CALLBACKS"`
+}
+
+type CacheConfig struct {
+	Size int           `env:"SIZE"`
+	TTL  time.Duration `env:"TTL"`
+}
+
+func TestGenericBuilder_DecodeTypes(t *testing.T) {
+	setEnvVars(t, map[string]string{
+		"DEC_PORTS":     "80, 443",
+		"DEC_TIMEOUTS":  "1s,2m",
+		"DEC_LABELS":    "team:core, tier:gold",
+		"DEC_WEIGHTS":   "a:1,b:2",
+		"DEC_MAX_CONNS": "42",
+		"DEC_DEBUG":     "true",
+		"DEC_ENDPOINT":  "https://api.example.com/v1",
+		"DEC_PROXY":     "http://proxy:3128",
+		"DEC_BIND_IP":   "10.0.0.1",
+		"DEC_START_AT":  "2024-05-01T10:00:00Z",
+		"DEC_PAIR":      `["a","b"]`,
+		"DEC_RATIO":     "0.5",
+		"DEC_CACHE_TTL": "30s",
+	})
+
+	cfg, err := New(&DecodeConfig{}).EnvPrefix("DEC_").Build()
+	require.NoError(t, err)
+
+	assert.Equal(t, []int{80, 443}, cfg.Ports)
+	assert.Equal(t, []time.Duration{time.Second, 2 * time.Minute}, cfg.Timeouts)
+	assert.Equal(t, map[string]string{"team": "core", "tier": "gold"}, cfg.Labels)
+	assert.Equal(t, map[string]int{"a": 1, "b": 2}, cfg.Weights)
+	require.NotNil(t, cfg.MaxConns)
+	assert.Equal(t, 42, *cfg.MaxConns)
+	require.NotNil(t, cfg.Debug)
+	assert.True(t, *cfg.Debug)
+	assert.Equal(t, "api.example.com", cfg.Endpoint.Host)
+	require.NotNil(t, cfg.Proxy)
+	assert.Equal(t, "proxy:3128", cfg.Proxy.Host)
+	assert.Equal(t, net.ParseIP("10.0.0.1"), cfg.BindIP)
+	assert.Equal(t, time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC), cfg.StartAt)
+	assert.Equal(t, jsonPair{Left: "a", Right: "b"}, cfg.Pair)
+	assert.Equal(t, float32(0.5), cfg.Ratio)
+
+	// Nil pointer structs are allocated only when one of their fields is set
+	require.NotNil(t, cfg.Cache)
+	assert.Equal(t, 30*time.Second, cfg.Cache.TTL)
+	assert.Nil(t, cfg.Unused)
+}
+
+func TestGenericBuilder_DecodeErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		wantErr string
+	}{
+		{
+			name:    "Invalid slice item",
+			env:     map[string]string{"DEC_PORTS": "80,http"},
+			wantErr: "invalid integer value for DEC_PORTS[1]",
+		},
+		{
+			name:    "Invalid map entry",
+			env:     map[string]string{"DEC_LABELS": "team"},
+			wantErr: `invalid map entry "team" for DEC_LABELS`,
+		},
+		{
+			name:    "Invalid map value",
+			env:     map[string]string{"DEC_WEIGHTS": "a:x"},
+			wantErr: "invalid integer value for DEC_WEIGHTS[a]",
+		},
+		{
+			name:    "Invalid TextUnmarshaler value",
+			env:     map[string]string{"DEC_START_AT": "yesterday"},
+			wantErr: "invalid time.Time value for DEC_START_AT",
+		},
+		{
+			name:    "Invalid nested pointer value",
+			env:     map[string]string{"DEC_CACHE_SIZE": "big"},
+			wantErr: "invalid integer value for DEC_CACHE_SIZE",
+		},
+		{
+			name:    "Unsupported type",
+			env:     map[string]string{"DEC_CALLBACKS": "1"},
+			wantErr: "unsupported type chan int for DEC_CALLBACKS",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setEnvVars(t, tt.env)
+
+			_, err := New(&DecodeConfig{}).EnvPrefix("DEC_").Build()
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
+
+func TestFormatValue(t *testing.T) {
+	maxConns := 7
+	tests := []struct {
+		name  string
+		value any
+		want  string
+	}{
+		{name: "Nil pointer", value: (*int)(nil), want: ""},
+		{name: "Pointer", value: &maxConns, want: "7"},
+		{name: "Typed slice", value: []time.Duration{time.Second, time.Minute}, want: "1s,1m0s"},
+		{name: "Map sorted by key", value: map[string]int{"b": 2, "a": 1}, want: "a:1,b:2"},
+		{name: "URL", value: url.URL{Scheme: "https", Host: "example.com"}, want: "https://example.com"},
+		{name: "IP", value: net.ParseIP("10.0.0.1"), want: "10.0.0.1"},
+		{name: "Time", value: time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC), want: "2024-05-01T00:00:00Z"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, formatValue(reflect.ValueOf(tt.value)))
+		})
+	}
+}
+
+// ByteSize is a size in bytes written with an optional binary unit, e.g. 512MiB
+type ByteSize int64
+
+func parseByteSize(raw string) (ByteSize, error) {
+	units := []struct {
+		suffix string
+		factor ByteSize
+	}{{"GiB", 1 << 30}, {"MiB", 1 << 20}, {"KiB", 1 << 10}, {"B", 1}}
+	for _, u := range units {
+		if n, ok := strings.CutSuffix(raw, u.suffix); ok {
+			v, err := strconv.ParseInt(n, 10, 64)
+			return ByteSize(v) * u.factor, err
+		}
+	}
+	v, err := strconv.ParseInt(raw, 10, 64)
+	return ByteSize(v), err
+}
+
+// byteQuantity is a struct type decoded as a whole by a registered decoder
+type byteQuantity struct {
+	N int64
+}
+
+func parseByteQuantity(raw string) (byteQuantity, error) {
+	size, err := parseByteSize(raw)
+	return byteQuantity{N: int64(size)}, err
+}
+
+type QuantityConfig struct {
+	Buffer byteQuantity  `json:"buffer" env:"BUFFER" default:"1KiB"`
+	Spill  *byteQuantity `json:"spill" env:"SPILL"`
+	Upload byteQuantity  `json:"upload" env:"UPLOAD"`
+}
+
+type DecoderConfig struct {
+	MaxBody  ByteSize         `json:"max_body" env:"MAX_BODY"`
+	Limits   []ByteSize       `json:"limits" env:"LIMITS"`
+	Pattern  *regexp.Regexp   `json:"-" env:"PATTERN"`
+	Mode     string           `json:"mode" env:"MODE"`
+	Timeout  time.Duration    `json:"timeout" env:"TIMEOUT"`
+	Optional *ByteSize        `json:"optional" env:"OPTIONAL"`
+	Quotas   map[string]int64 `json:"-" env:"QUOTAS"`
+}
+
+func TestGenericBuilder_Decoder(t *testing.T) {
+	newBuilder := func() *Builder[*DecoderConfig] {
+		b := New(&DecoderConfig{}).EnvPrefix("DCD_")
+		RegisterDecoder(b, parseByteSize)
+		RegisterDecoder(b, regexp.Compile)
+		return b.Decoder(reflect.TypeOf(""), func(raw string) (any, error) {
+			return strings.ToLower(raw), nil
+		})
+	}
+
+	t.Run("Env", func(t *testing.T) {
+		setEnvVars(t, map[string]string{
+			"DCD_MAX_BODY": "512MiB",
+			"DCD_LIMITS":   "1KiB, 2KiB",
+			"DCD_PATTERN":  "^v[0-9]+$",
+			"DCD_MODE":     "FAST",
+			"DCD_OPTIONAL": "3B",
+		})
+
+		cfg, err := newBuilder().Build()
+		require.NoError(t, err)
+		assert.Equal(t, ByteSize(512<<20), cfg.MaxBody)
+		assert.Equal(t, []ByteSize{1 << 10, 2 << 10}, cfg.Limits)
+		require.NotNil(t, cfg.Pattern)
+		assert.True(t, cfg.Pattern.MatchString("v12"))
+		assert.Equal(t, "fast", cfg.Mode)
+		require.NotNil(t, cfg.Optional)
+		assert.Equal(t, ByteSize(3), *cfg.Optional)
+	})
+
+	t.Run("Flags", func(t *testing.T) {
+		cfg, err := newBuilder().Flags([]string{"--max-body", "2KiB"}).Build()
+		require.NoError(t, err)
+		assert.Equal(t, ByteSize(2<<10), cfg.MaxBody)
+	})
+
+	t.Run("Config file strings", func(t *testing.T) {
+		configPath := writeFile(t, t.TempDir(), "config.json", `{"max_body": "1GiB", "timeout": "30s", "optional": 7}`)
+
+		cfg, err := newBuilder().File(&configPath).Build()
+		require.NoError(t, err)
+		assert.Equal(t, ByteSize(1<<30), cfg.MaxBody)
+		assert.Equal(t, 30*time.Second, cfg.Timeout)
+		require.NotNil(t, cfg.Optional)
+		assert.Equal(t, ByteSize(7), *cfg.Optional) // Non string values keep their JSON decoding
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		setEnvVars(t, map[string]string{
+			"DCD_MAX_BODY": "lots",
+			"DCD_PATTERN":  "[",
+		})
+
+		_, err := newBuilder().Build()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "invalid confbuilder.ByteSize value for DCD_MAX_BODY")
+		assert.Contains(t, err.Error(), "invalid *regexp.Regexp value for DCD_PATTERN")
+	})
+
+	t.Run("Struct types", func(t *testing.T) {
+		configPath := writeFile(t, t.TempDir(), "config.json", `{"upload": "2MiB"}`)
+
+		b := New(&QuantityConfig{}).
+			EnvPrefix("DCD_").
+			Env(MapEnv{"DCD_SPILL": "512MiB"}).
+			File(&configPath).
+			Flags([]string{"--buffer", "4KiB"})
+		cfg, err := RegisterDecoder(b, parseByteQuantity).Build()
+		require.NoError(t, err)
+		assert.Equal(t, byteQuantity{N: 4 << 10}, cfg.Buffer)
+		require.NotNil(t, cfg.Spill)
+		assert.Equal(t, byteQuantity{N: 512 << 20}, *cfg.Spill)
+		assert.Equal(t, byteQuantity{N: 2 << 20}, cfg.Upload)
+
+		b = New(&QuantityConfig{}).Env(MapEnv{})
+		cfg, err = RegisterDecoder(b, parseByteQuantity).Build()
+		require.NoError(t, err)
+		assert.Equal(t, byteQuantity{N: 1 << 10}, cfg.Buffer)
+	})
+
+	t.Run("Mismatched decoder result", func(t *testing.T) {
+		setEnvVars(t, map[string]string{"DCD_QUOTAS": "a:1"})
+
+		b := New(&DecoderConfig{}).EnvPrefix("DCD_").Decoder(reflect.TypeOf(int64(0)), func(raw string) (any, error) {
+			return raw, nil
+		})
+		_, err := b.Build()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "decoder for int64 returned string for DCD_QUOTAS[a]")
+	})
+}
@@ -0,0 +1,65 @@
+package confbuilder
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// defaultTag is the struct tag holding the default value of a field, e.g. `default:"30s"`
+const defaultTag = "default"
+
+// Defaults returns a configuration built only from the default tags of T, pointer types are
+// allocated
+func Defaults[T any]() (T, error) {
+	var config T
+	target := any(&config)
+	if t := reflect.TypeOf(config); t != nil && t.Kind() == reflect.Ptr {
+		config = reflect.New(t.Elem()).Interface().(T)
+		target = config
+	}
+
+	err := applyDefaults(target, envNaming{tag: "env"}, defaultDecoders())
+	return config, err
+}
+
+// applyDefaults sets every zero valued or empty field of target carrying a default tag, decoding the
+// tag like an env value. Nested structs and the elements of slices of structs are visited
+// too, nil pointer structs are allocated when one of their fields gets a default
+func applyDefaults(target any, naming envNaming, d decoders) error {
+	return walkFields(target, naming, func(f fieldInfo) error {
+		// Elements already present in slices of structs get their own defaults
+		if f.value.Kind() == reflect.Slice && isStructType(f.value.Type().Elem()) {
+			for i := 0; i < f.value.Len(); i++ {
+				elem := f.value.Index(i)
+				if elem.Kind() == reflect.Ptr {
+					if elem.IsNil() {
+						continue
+					}
+					elem = elem.Elem()
+				}
+				if err := applyDefaults(elem.Addr().Interface(), naming, d); err != nil {
+					return fmt.Errorf("error loading sub config field %s[%d]: %w", f.path, i, err)
+				}
+			}
+		}
+
+		// Cloning turns nil slices and maps into empty ones, both count as unset
+		raw, ok := f.field.Tag.Lookup(defaultTag)
+		if !ok || !isEmptyValue(f.value) {
+			return nil
+		}
+		if err := d.setFieldValue(f.value, f.path+" default", raw); err != nil {
+			return err
+		}
+		f.ensure()
+		return nil
+	})
+}
+
+// isStructType reports whether t is a struct or a pointer to a struct walked field by field
+func isStructType(t reflect.Type) bool {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t.Kind() == reflect.Struct && !isLeafType(t)
+}
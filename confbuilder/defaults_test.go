@@ -0,0 +1,128 @@
+package confbuilder
+
+import (
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type TaggedConfig struct {
+	Port     int           `json:"port" env:"PORT" default:"8080"`
+	Timeout  time.Duration `json:"timeout" env:"TIMEOUT" default:"30s"`
+	LogLevel slog.Level    `json:"log_level" env:"LOG_LEVEL" default:"warn"`
+	Hosts    []string      `json:"hosts" env:"HOSTS" default:"a.example.com,b.example.com"`
+	Name     string        `json:"name" env:"NAME"`
+	Database TaggedDB      `json:"database" env:"DB"`
+	Cache    *TaggedCache  `json:"cache" env:"CACHE"`
+	Backends []TaggedDB    `json:"backends"`
+}
+
+type TaggedDB struct {
+	Host string `json:"host" env:"HOST" default:"localhost"`
+	Port int    `json:"port" env:"PORT" default:"5432"`
+}
+
+type TaggedCache struct {
+	Size int `json:"size" env:"SIZE" default:"128"`
+}
+
+func TestDefaults(t *testing.T) {
+	cfg, err := Defaults[*TaggedConfig]()
+	require.NoError(t, err)
+
+	assert.Equal(t, 8080, cfg.Port)
+	assert.Equal(t, 30*time.Second, cfg.Timeout)
+	assert.Equal(t, slog.LevelWarn, cfg.LogLevel)
+	assert.Equal(t, []string{"a.example.com", "b.example.com"}, cfg.Hosts)
+	assert.Equal(t, "", cfg.Name)
+	assert.Equal(t, TaggedDB{Host: "localhost", Port: 5432}, cfg.Database)
+	require.NotNil(t, cfg.Cache)
+	assert.Equal(t, 128, cfg.Cache.Size)
+	assert.Empty(t, cfg.Backends)
+
+	value, err := Defaults[TaggedDB]()
+	require.NoError(t, err)
+	assert.Equal(t, TaggedDB{Host: "localhost", Port: 5432}, value)
+}
+
+func TestGenericBuilder_DefaultTags(t *testing.T) {
+	tests := []struct {
+		name     string
+		defaults *TaggedConfig
+		env      map[string]string
+		file     string
+		validate func(*testing.T, *TaggedConfig)
+	}{
+		{
+			name:     "Tags fill zero fields",
+			defaults: &TaggedConfig{},
+			validate: func(t *testing.T, cfg *TaggedConfig) {
+				assert.Equal(t, 8080, cfg.Port)
+				assert.Equal(t, "localhost", cfg.Database.Host)
+			},
+		},
+		{
+			name:     "Explicit defaults win over tags",
+			defaults: &TaggedConfig{Port: 9000, Database: TaggedDB{Host: "db"}},
+			validate: func(t *testing.T, cfg *TaggedConfig) {
+				assert.Equal(t, 9000, cfg.Port)
+				assert.Equal(t, "db", cfg.Database.Host)
+				assert.Equal(t, 5432, cfg.Database.Port)
+			},
+		},
+		{
+			name:     "Env and file override tags",
+			defaults: &TaggedConfig{},
+			env:      map[string]string{"TAG_TIMEOUT": "5s"},
+			file:     `{"port": 7000}`,
+			validate: func(t *testing.T, cfg *TaggedConfig) {
+				assert.Equal(t, 7000, cfg.Port)
+				assert.Equal(t, 5*time.Second, cfg.Timeout)
+			},
+		},
+		{
+			name: "Slices of structs",
+			defaults: &TaggedConfig{
+				Backends: []TaggedDB{{Host: "primary"}, {Port: 6432}},
+			},
+			validate: func(t *testing.T, cfg *TaggedConfig) {
+				assert.Equal(t, []TaggedDB{
+					{Host: "primary", Port: 5432},
+					{Host: "localhost", Port: 6432},
+				}, cfg.Backends)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setEnvVars(t, tt.env)
+
+			b := New(tt.defaults).EnvPrefix("TAG_")
+			if tt.file != "" {
+				configPath := writeFile(t, t.TempDir(), "config.json", tt.file)
+				b.File(&configPath)
+			}
+
+			cfg, err := b.Build()
+			require.NoError(t, err)
+			tt.validate(t, cfg)
+		})
+	}
+}
+
+func TestGenericBuilder_InvalidDefaultTag(t *testing.T) {
+	type Config struct {
+		Timeout time.Duration `default:"soon"`
+	}
+
+	_, err := New(&Config{}).Build()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to apply default values: invalid duration value for Timeout default")
+
+	_, err = Defaults[Config]()
+	require.Error(t, err)
+}
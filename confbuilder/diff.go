@@ -0,0 +1,153 @@
+package confbuilder
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/jinzhu/copier"
+)
+
+// reloadTag marks fields whose changes cannot be applied live, e.g. `reload:"restart"`. Tagging
+// a nested struct covers all of its fields
+const reloadTag = "reload"
+
+// compareNaming walks the fields compared by Diff and Hash. Without the decoders of a builder,
+// struct types holding only unexported fields, usually decoded by a custom decoder, are
+// compared as a whole rather than walked into no field at all
+var compareNaming = envNaming{tag: "env", opaque: true}
+
+// Change describes a configuration field whose value differs between two configurations
+type Change struct {
+	Path    string // Go field path, e.g. Database.Port
+	Old     any    // Previous value, masked for secret fields
+	New     any    // Current value, masked for secret fields
+	Restart bool   // Set when the field is tagged reload:"restart"
+}
+
+// Diff returns the fields whose values differ between oldCfg and newCfg, walking the same
+// fields as the env layer. Values of fields tagged secret:"true" are redacted
+func Diff[T any](oldCfg, newCfg T) ([]Change, error) {
+	return diffConfigs(configTarget(&oldCfg), configTarget(&newCfg), compareNaming)
+}
+
+// RequiresRestart reports whether one of changes concerns a field tagged reload:"restart"
+func RequiresRestart(changes []Change) bool {
+	for _, c := range changes {
+		if c.Restart {
+			return true
+		}
+	}
+	return false
+}
+
+// Hash returns a stable SHA-256 digest of the content of cfg, e.g. to label metrics with the
+// active configuration. Equal configurations always hash to the same value
+func Hash[T any](cfg T) (string, error) {
+	h := sha256.New()
+	err := walkFields(configTarget(&cfg), compareNaming, func(f fieldInfo) error {
+		// JSON keeps slices holding separators apart, e.g. [a b] and [a,b], and sorts map keys
+		value, err := json.Marshal(f.value.Interface())
+		if err != nil {
+			value = []byte(formatValue(f.value))
+		}
+		if f.value.Kind() == reflect.Struct && !hasExportedFields(f.value.Type()) && !isLeafType(f.value.Type()) {
+			// JSON renders such structs as {}, their unexported fields are printed instead
+			value = []byte(fmt.Sprintf("%#v", f.value.Interface()))
+		}
+		_, err = fmt.Fprintf(h, "%s=%s\n", f.path, value)
+		return err
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to hash config: %w", err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// diffConfigs returns the leaf fields whose values differ between oldTarget and newTarget
+func diffConfigs(oldTarget, newTarget any, naming envNaming) ([]Change, error) {
+	oldValues := map[string]any{}
+	err := walkFields(oldTarget, naming, func(f fieldInfo) error {
+		oldValues[f.path] = f.value.Interface()
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	root := reflect.TypeOf(newTarget).Elem()
+	var changes []Change
+	err = walkFields(newTarget, naming, func(f fieldInfo) error {
+		oldValue, newValue := oldValues[f.path], f.value.Interface()
+		if reflect.DeepEqual(oldValue, newValue) {
+			return nil
+		}
+		switch {
+		case isSecret(f.field):
+			oldValue, newValue = redactedChange(oldValue), redactedChange(newValue)
+		case isStructCollection(f.value.Type()):
+			var err error
+			if oldValue, err = redactedCollection(oldValue, naming); err != nil {
+				return err
+			}
+			if newValue, err = redactedCollection(newValue, naming); err != nil {
+				return err
+			}
+		}
+		changes = append(changes, Change{Path: f.path, Old: oldValue, New: newValue, Restart: needsRestart(root, f.path)})
+		return nil
+	})
+	return changes, err
+}
+
+// redactedChange masks a secret value reported in a change
+func redactedChange(value any) any {
+	v := reflect.ValueOf(value)
+	if !v.IsValid() || isEmptyValue(v) {
+		return value
+	}
+	if v.Kind() == reflect.String {
+		return redactSecret(v.String())
+	}
+	return redactedValue
+}
+
+// redactedCollection returns a copy of a slice or map of structs whose elements have their
+// secret fields masked
+func redactedCollection(value any, naming envNaming) (any, error) {
+	v := reflect.ValueOf(value)
+	if !v.IsValid() || v.IsNil() {
+		return value, nil
+	}
+
+	clone := reflect.New(v.Type())
+	if err := copier.CopyWithOption(clone.Interface(), value, copier.Option{DeepCopy: true}); err != nil {
+		return nil, fmt.Errorf("failed to copy changed value: %w", err)
+	}
+	if err := redactElements(clone.Elem(), naming); err != nil {
+		return nil, err
+	}
+	return clone.Elem().Interface(), nil
+}
+
+// needsRestart reports whether the field at path, or one of the structs holding it, is tagged
+// reload:"restart"
+func needsRestart(t reflect.Type, path string) bool {
+	for _, name := range strings.Split(path, ".") {
+		if t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+		field, ok := t.FieldByName(name)
+		if !ok {
+			return false
+		}
+		if field.Tag.Get(reloadTag) == "restart" {
+			return true
+		}
+		t = field.Type
+	}
+	return false
+}
@@ -0,0 +1,167 @@
+package confbuilder
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type DiffConfig struct {
+	Port     int                     `env:"PORT" reload:"restart"`
+	Timeout  time.Duration           `env:"TIMEOUT"`
+	DSN      string                  `env:"DSN" secret:"true"`
+	Token    []byte                  `env:"TOKEN" secret:"true"`
+	Tags     []string                `env:"TAGS"`
+	Database DiffDatabase            `env:"DB" reload:"restart"`
+	Cache    *DiffDatabase           `env:"CACHE"`
+	Labels   map[string]int          `env:"LABELS"`
+	Ups      []DiffUpstream          `env:"UPS"`
+	Tenants  map[string]DiffUpstream `env:"TENANTS"`
+	Size     diffSize                `env:"SIZE"`
+}
+
+type DiffDatabase struct {
+	Host string `env:"HOST"`
+}
+
+type DiffUpstream struct {
+	URL   string `env:"URL"`
+	Token string `env:"TOKEN" secret:"true"`
+}
+
+// diffSize only holds unexported fields, as types decoded by a custom decoder often do
+type diffSize struct {
+	n int64
+}
+
+func newDiffConfig() DiffConfig {
+	return DiffConfig{
+		Port:     8080,
+		Timeout:  time.Second,
+		DSN:      "postgres://app:old@db/app",
+		Tags:     []string{"a"},
+		Database: DiffDatabase{Host: "db"},
+		Labels:   map[string]int{"x": 1, "y": 2},
+		Ups:      []DiffUpstream{{URL: "u", Token: "UPSECRET"}},
+		Tenants:  map[string]DiffUpstream{"acme": {URL: "a", Token: "TENANTSECRET"}},
+		Size:     diffSize{n: 512},
+	}
+}
+
+func TestDiff(t *testing.T) {
+	tests := []struct {
+		name    string
+		update  func(c *DiffConfig)
+		changes []Change
+		restart bool
+	}{
+		{
+			name:   "Unchanged",
+			update: func(c *DiffConfig) {},
+		},
+		{
+			name:    "Live change",
+			update:  func(c *DiffConfig) { c.Timeout = 2 * time.Second; c.Tags = append(c.Tags, "b") },
+			changes: []Change{{Path: "Timeout", Old: time.Second, New: 2 * time.Second}, {Path: "Tags", Old: []string{"a"}, New: []string{"a", "b"}}},
+		},
+		{
+			name:    "Restart field",
+			update:  func(c *DiffConfig) { c.Port = 9090 },
+			changes: []Change{{Path: "Port", Old: 8080, New: 9090, Restart: true}},
+			restart: true,
+		},
+		{
+			name:    "Restart struct",
+			update:  func(c *DiffConfig) { c.Database.Host = "replica" },
+			changes: []Change{{Path: "Database.Host", Old: "db", New: "replica", Restart: true}},
+			restart: true,
+		},
+		{
+			name:    "Nil pointer struct",
+			update:  func(c *DiffConfig) { c.Cache = &DiffDatabase{Host: "cache"} },
+			changes: []Change{{Path: "Cache.Host", Old: "", New: "cache"}},
+		},
+		{
+			name:   "Secrets redacted",
+			update: func(c *DiffConfig) { c.DSN = "postgres://app:new@db/app"; c.Token = []byte("t0k3n") },
+			changes: []Change{
+				{Path: "DSN", Old: "postgres://app:xxxxx@db/app", New: "postgres://app:xxxxx@db/app"},
+				{Path: "Token", Old: []byte(nil), New: "xxxxx"},
+			},
+		},
+		{
+			name: "Secrets redacted in collections",
+			update: func(c *DiffConfig) {
+				c.Ups = []DiffUpstream{{URL: "u", Token: "NEWSECRET"}}
+				c.Tenants = map[string]DiffUpstream{"acme": {URL: "b", Token: "TENANTSECRET"}}
+			},
+			changes: []Change{
+				{Path: "Ups", Old: []DiffUpstream{{URL: "u", Token: "xxxxx"}}, New: []DiffUpstream{{URL: "u", Token: "xxxxx"}}},
+				{Path: "Tenants", Old: map[string]DiffUpstream{"acme": {URL: "a", Token: "xxxxx"}}, New: map[string]DiffUpstream{"acme": {URL: "b", Token: "xxxxx"}}},
+			},
+		},
+		{
+			name:    "Struct without exported fields",
+			update:  func(c *DiffConfig) { c.Size = diffSize{n: 1024} },
+			changes: []Change{{Path: "Size", Old: diffSize{n: 512}, New: diffSize{n: 1024}}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			oldCfg, newCfg := newDiffConfig(), newDiffConfig()
+			tt.update(&newCfg)
+
+			changes, err := Diff(oldCfg, newCfg)
+			require.NoError(t, err)
+			assert.Equal(t, tt.changes, changes)
+			assert.Equal(t, tt.restart, RequiresRestart(changes))
+
+			// Pointer configurations are compared the same way
+			changes, err = Diff(&oldCfg, &newCfg)
+			require.NoError(t, err)
+			assert.Equal(t, tt.changes, changes)
+		})
+	}
+}
+
+func TestHash(t *testing.T) {
+	base := newDiffConfig()
+	base.Tags = []string{"a", "b"}
+	hash, err := Hash(base)
+	require.NoError(t, err)
+	assert.Len(t, hash, 64)
+
+	// Equal content hashes the same, whatever the map order or pointer indirection
+	same := newDiffConfig()
+	same.Tags = []string{"a", "b"}
+	same.Labels = map[string]int{"y": 2, "x": 1}
+	sameHash, err := Hash(&same)
+	require.NoError(t, err)
+	assert.Equal(t, hash, sameHash)
+
+	tests := []struct {
+		name   string
+		update func(c *DiffConfig)
+	}{
+		{name: "Scalar", update: func(c *DiffConfig) { c.Port = 9090 }},
+		{name: "Secret", update: func(c *DiffConfig) { c.DSN = "postgres://app:new@db/app" }},
+		{name: "Slice", update: func(c *DiffConfig) { c.Tags = []string{"a,b"} }},
+		{name: "Nested", update: func(c *DiffConfig) { c.Database.Host = "replica" }},
+		{name: "Collection secret", update: func(c *DiffConfig) { c.Ups[0].Token = "NEWSECRET" }},
+		{name: "Struct without exported fields", update: func(c *DiffConfig) { c.Size = diffSize{n: 1024} }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := newDiffConfig()
+			cfg.Tags = []string{"a", "b"}
+			tt.update(&cfg)
+			changed, err := Hash(cfg)
+			require.NoError(t, err)
+			assert.NotEqual(t, hash, changed)
+		})
+	}
+}
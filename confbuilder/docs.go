@@ -0,0 +1,369 @@
+package confbuilder
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// DocFormat selects how Docs renders the configuration reference
+type DocFormat string
+
+const (
+	// DocMarkdown renders a Markdown table of the env variables
+	DocMarkdown DocFormat = "markdown"
+	// DocDotenv renders a sample .env file holding the defaults
+	DocDotenv DocFormat = "dotenv"
+	// DocJSONSchema renders a JSON Schema of the configuration files
+	DocJSONSchema DocFormat = "jsonschema"
+)
+
+// descTag is the struct tag holding the description of a field
+const descTag = "desc"
+
+// FieldDoc documents one env bound configuration field
+type FieldDoc struct {
+	Path        string // Go field path, e.g. Database.Port or Upstreams[n].URL for collection elements
+	EnvVar      string // Env variable including the prefix, e.g. APP_UPSTREAMS_<n>_URL for collection elements
+	Type        string // Go type, e.g. time.Duration
+	Default     string // Default value from the builder's default config and default tags, masked for secrets
+	Validate    string // Rules of the validate tag
+	Description string // Text of the desc tag
+	Secret      bool   // Set for fields tagged secret:"true"
+}
+
+// FieldDocs lists every field bound to an env variable, walked with the same rules as the env
+// layer, in declaration order
+func (b *Builder[T]) FieldDocs() ([]FieldDoc, error) {
+	target, err := b.defaultsTarget()
+	if err != nil {
+		return nil, err
+	}
+	return fieldDocs(target, b.naming(), b.decoders, b.envPrefix, "")
+}
+
+// Docs renders the reference documentation of the configuration, keeping README tables,
+// sample .env files and editor schemas in sync with the code
+func (b *Builder[T]) Docs(format DocFormat) (string, error) {
+	if format == DocJSONSchema {
+		target, err := b.defaultsTarget()
+		if err != nil {
+			return "", err
+		}
+		schema, err := objectSchema(target, b.naming(), b.decoders)
+		if err != nil {
+			return "", err
+		}
+		schema["$schema"] = "https://json-schema.org/draft/2020-12/schema"
+		schema["title"] = reflect.TypeOf(b.config).String()
+
+		data, err := json.MarshalIndent(schema, "", "  ")
+		if err != nil {
+			return "", fmt.Errorf("failed to render JSON schema: %w", err)
+		}
+		return string(data), nil
+	}
+
+	docs, err := b.FieldDocs()
+	if err != nil {
+		return "", err
+	}
+
+	var sb strings.Builder
+	switch format {
+	case DocMarkdown:
+		sb.WriteString("| Variable | Type | Default | Validation | Description |\n")
+		sb.WriteString("| --- | --- | --- | --- | --- |\n")
+		for _, d := range docs {
+			fmt.Fprintf(&sb, "| `%s` | %s | %s | %s | %s |\n",
+				d.EnvVar, markdownCell(d.Type), markdownCode(d.Default), markdownCode(d.Validate), markdownCell(d.Description))
+		}
+
+	case DocDotenv:
+		for i, d := range docs {
+			if i > 0 {
+				sb.WriteString("\n")
+			}
+			if d.Description != "" {
+				fmt.Fprintf(&sb, "# %s\n", d.Description)
+			}
+			fmt.Fprintf(&sb, "# Type: %s", d.Type)
+			if d.Validate != "" {
+				fmt.Fprintf(&sb, ", validation: %s", d.Validate)
+			}
+			sb.WriteString("\n")
+
+			value := d.Default
+			if d.Secret {
+				value = "" // Secrets are never written to sample files
+			}
+			fmt.Fprintf(&sb, "%s=%s\n", d.EnvVar, dotenvValue(value))
+		}
+
+	default:
+		return "", fmt.Errorf("unsupported docs format %q", format)
+	}
+	return sb.String(), nil
+}
+
+// defaultsTarget returns a copy of the default configuration with its default tags applied
+func (b *Builder[T]) defaultsTarget() (any, error) {
+	_, target, err := cloneConfig(b.config)
+	if err != nil {
+		return nil, err
+	}
+	if err := applyDefaults(target, b.naming(), b.decoders); err != nil {
+		return nil, fmt.Errorf("failed to apply default values: %w", err)
+	}
+	return target, nil
+}
+
+// fieldDocs documents the env bound fields of target, collection elements are documented once
+// with an <n> or <key> placeholder
+func fieldDocs(target any, naming envNaming, d decoders, envPrefix, pathPrefix string) ([]FieldDoc, error) {
+	var docs []FieldDoc
+	err := walkFields(target, naming, func(f fieldInfo) error {
+		if f.envKey == "" {
+			return nil
+		}
+		path := joinPath(pathPrefix, f.path)
+
+		if t := f.value.Type(); isStructCollection(t) {
+			placeholder, index := "<key>", "[key]"
+			if t.Kind() == reflect.Slice {
+				placeholder, index = "<n>", "[n]"
+			}
+			elem, err := defaultElement(t.Elem(), naming, d)
+			if err != nil {
+				return err
+			}
+			elemDocs, err := fieldDocs(elem, naming, d, envPrefix+f.envKey+"_"+placeholder+"_", path+index)
+			docs = append(docs, elemDocs...)
+			return err
+		}
+
+		doc := FieldDoc{
+			Path:        path,
+			EnvVar:      envPrefix + f.envKey,
+			Type:        f.value.Type().String(),
+			Validate:    f.field.Tag.Get("validate"),
+			Description: f.field.Tag.Get(descTag),
+			Secret:      isSecret(f.field),
+		}
+		if !isEmptyValue(f.value) {
+			doc.Default = displayValue(f, formatValue(f.value))
+		}
+		docs = append(docs, doc)
+		return nil
+	})
+	return docs, err
+}
+
+// defaultElement returns a pointer to a new collection element holding its default tags
+func defaultElement(elemType reflect.Type, naming envNaming, d decoders) (any, error) {
+	if elemType.Kind() == reflect.Ptr {
+		elemType = elemType.Elem()
+	}
+	elem := reflect.New(elemType).Interface()
+	return elem, applyDefaults(elem, naming, d)
+}
+
+// objectSchema returns the JSON Schema of the config file object decoded into target
+func objectSchema(target any, naming envNaming, d decoders) (map[string]any, error) {
+	root := map[string]any{"type": "object", "properties": map[string]any{}}
+
+	err := walkFields(target, naming, func(f fieldInfo) error {
+		if len(f.jsonPath) == 0 {
+			return nil
+		}
+
+		// Nested structs become nested objects
+		parent := root
+		for _, key := range f.jsonPath[:len(f.jsonPath)-1] {
+			props := parent["properties"].(map[string]any)
+			child, ok := props[key].(map[string]any)
+			if !ok {
+				child = map[string]any{"type": "object", "properties": map[string]any{}}
+				props[key] = child
+			}
+			parent = child
+		}
+
+		schema, err := typeSchema(f.value.Type(), naming, d)
+		if err != nil {
+			return err
+		}
+		if desc := f.field.Tag.Get(descTag); desc != "" {
+			schema["description"] = desc
+		}
+		if !isEmptyValue(f.value) && !isSecret(f.field) && !isStructCollection(f.value.Type()) {
+			if hasTextForm(f.value.Type(), d) {
+				schema["default"] = formatValue(f.value)
+			} else {
+				schema["default"] = f.value.Interface()
+			}
+		}
+
+		rules, _, _ := strings.Cut(f.field.Tag.Get("validate"), ",dive")
+		key := f.jsonPath[len(f.jsonPath)-1]
+		if applyRules(schema, f.value.Type(), rules) {
+			required, _ := parent["required"].([]string)
+			parent["required"] = append(required, key)
+		}
+		parent["properties"].(map[string]any)[key] = schema
+		return nil
+	})
+	return root, err
+}
+
+// typeSchema returns the JSON Schema of values of type t in config files
+func typeSchema(t reflect.Type, naming envNaming, d decoders) (map[string]any, error) {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	// Registered decoders and self decoding types read their textual form
+	if hasTextForm(t, d) {
+		if native := kindSchemaType(t.Kind()); native != "" && native != "string" {
+			return map[string]any{"type": []string{"string", native}}, nil
+		}
+		return map[string]any{"type": "string"}, nil
+	}
+	if reflect.PointerTo(t).Implements(jsonUnmarshalerType) {
+		return map[string]any{}, nil
+	}
+
+	switch t.Kind() {
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]any{"type": "string"}, nil
+		}
+		items, err := typeSchema(t.Elem(), naming, d)
+		return map[string]any{"type": "array", "items": items}, err
+
+	case reflect.Map:
+		values, err := typeSchema(t.Elem(), naming, d)
+		return map[string]any{"type": "object", "additionalProperties": values}, err
+
+	case reflect.Struct:
+		elem, err := defaultElement(t, naming, d)
+		if err != nil {
+			return nil, err
+		}
+		return objectSchema(elem, naming, d)
+	}
+
+	if native := kindSchemaType(t.Kind()); native != "" {
+		return map[string]any{"type": native}, nil
+	}
+	return map[string]any{}, nil
+}
+
+// hasTextForm reports whether values of type t are written in config files as strings decoded
+// by a registered decoder or by the type itself
+func hasTextForm(t reflect.Type, d decoders) bool {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	_, ok := d[t]
+	return ok || t == urlType || reflect.PointerTo(t).Implements(textUnmarshalerType)
+}
+
+// isEmptyValue reports whether v holds no default worth documenting
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Slice, reflect.Map:
+		return v.Len() == 0
+	}
+	return v.IsZero()
+}
+
+// kindSchemaType maps a reflect kind to a JSON Schema type, empty when there is none
+func kindSchemaType(kind reflect.Kind) string {
+	switch kind {
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "integer"
+	case reflect.Float32, reflect.Float64:
+		return "number"
+	}
+	return ""
+}
+
+// applyRules translates the validate rules of a field into schema keywords and reports whether
+// the field is required
+func applyRules(schema map[string]any, t reflect.Type, rules string) bool {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	required := false
+	for _, rule := range strings.Split(rules, ",") {
+		name, param, _ := strings.Cut(rule, "=")
+		switch name {
+		case "required":
+			required = true
+		case "min", "gte":
+			schema[boundKeyword(t, "minimum", "minLength", "minItems")] = schemaNumber(param)
+		case "max", "lte":
+			schema[boundKeyword(t, "maximum", "maxLength", "maxItems")] = schemaNumber(param)
+		case "oneof":
+			var enum []any
+			for _, v := range strings.Fields(param) {
+				if kindSchemaType(t.Kind()) == "string" {
+					enum = append(enum, v)
+				} else {
+					enum = append(enum, schemaNumber(v))
+				}
+			}
+			schema["enum"] = enum
+		}
+	}
+	return required
+}
+
+// boundKeyword returns the keyword bounding values of type t: numbers, string lengths or item counts
+func boundKeyword(t reflect.Type, number, length, items string) string {
+	switch t.Kind() {
+	case reflect.String:
+		return length
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return items
+	}
+	return number
+}
+
+// schemaNumber renders a numeric rule parameter as a JSON number when possible
+func schemaNumber(param string) any {
+	if n, err := strconv.ParseFloat(param, 64); err == nil {
+		return n
+	}
+	return param
+}
+
+// markdownCell escapes a value for a Markdown table cell
+func markdownCell(s string) string {
+	return strings.ReplaceAll(s, "|", `\|`)
+}
+
+// markdownCode renders a non empty value as inline code inside a table cell
+func markdownCode(s string) string {
+	if s == "" {
+		return ""
+	}
+	return "`" + markdownCell(s) + "`"
+}
+
+// dotenvValue quotes values that .env parsers would otherwise split or truncate
+func dotenvValue(s string) string {
+	if strings.ContainsAny(s, " \t#\"'\\\n") {
+		return strconv.Quote(s)
+	}
+	return s
+}
@@ -0,0 +1,116 @@
+package confbuilder
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type DocsConfig struct {
+	Port      int             `json:"port" env:"PORT" validate:"required,min=1,max=65535" desc:"HTTP listen port"`
+	Timeout   time.Duration   `json:"timeout" env:"TIMEOUT" default:"30s" desc:"Request timeout"`
+	Mode      string          `json:"mode" env:"MODE" validate:"oneof=fast safe" desc:"Processing | mode"`
+	DSN       string          `json:"dsn" env:"DSN" secret:"true"`
+	Greeting  string          `json:"greeting" env:"GREETING"`
+	Internal  string          `json:"internal"`
+	Database  DocsDatabase    `json:"database" env:"DB"`
+	Upstreams []DocsUpstream  `json:"upstreams" env:"UPSTREAMS"`
+	Labels    map[string]bool `json:"labels" env:"LABELS"`
+}
+
+type DocsDatabase struct {
+	Host string `json:"host" env:"HOST" validate:"required" desc:"Database host"`
+}
+
+type DocsUpstream struct {
+	URL    string `json:"url" env:"URL" validate:"required"`
+	Weight int    `json:"weight" env:"WEIGHT" default:"1"`
+}
+
+func newDocsBuilder() *Builder[*DocsConfig] {
+	return New(&DocsConfig{
+		Port:     8080,
+		DSN:      "postgres://app:hunter2@db/app",
+		Greeting: "hello # world",
+		Database: DocsDatabase{Host: "localhost"},
+	}).EnvPrefix("APP_")
+}
+
+func TestGenericBuilder_FieldDocs(t *testing.T) {
+	docs, err := newDocsBuilder().FieldDocs()
+	require.NoError(t, err)
+
+	assert.Equal(t, []FieldDoc{
+		{Path: "Port", EnvVar: "APP_PORT", Type: "int", Default: "8080", Validate: "required,min=1,max=65535", Description: "HTTP listen port"},
+		{Path: "Timeout", EnvVar: "APP_TIMEOUT", Type: "time.Duration", Default: "30s", Description: "Request timeout"},
+		{Path: "Mode", EnvVar: "APP_MODE", Type: "string", Validate: "oneof=fast safe", Description: "Processing | mode"},
+		{Path: "DSN", EnvVar: "APP_DSN", Type: "string", Default: "postgres://app:xxxxx@db/app", Secret: true},
+		{Path: "Greeting", EnvVar: "APP_GREETING", Type: "string", Default: "hello # world"},
+		{Path: "Database.Host", EnvVar: "APP_DB_HOST", Type: "string", Default: "localhost", Validate: "required", Description: "Database host"},
+		{Path: "Upstreams[n].URL", EnvVar: "APP_UPSTREAMS_<n>_URL", Type: "string", Validate: "required"},
+		{Path: "Upstreams[n].Weight", EnvVar: "APP_UPSTREAMS_<n>_WEIGHT", Type: "int", Default: "1"},
+		{Path: "Labels", EnvVar: "APP_LABELS", Type: "map[string]bool"},
+	}, docs)
+}
+
+func TestGenericBuilder_Docs(t *testing.T) {
+	b := newDocsBuilder()
+
+	t.Run("Markdown", func(t *testing.T) {
+		out, err := b.Docs(DocMarkdown)
+		require.NoError(t, err)
+		assert.Contains(t, out, "| Variable | Type | Default | Validation | Description |\n| --- | --- | --- | --- | --- |\n")
+		assert.Contains(t, out, "| `APP_PORT` | int | `8080` | `required,min=1,max=65535` | HTTP listen port |\n")
+		assert.Contains(t, out, "| `APP_MODE` | string |  | `oneof=fast safe` | Processing \\| mode |\n")
+		assert.Contains(t, out, "| `APP_DSN` | string | `postgres://app:xxxxx@db/app` |  |  |\n")
+		assert.NotContains(t, out, "hunter2")
+	})
+
+	t.Run("Dotenv", func(t *testing.T) {
+		out, err := b.Docs(DocDotenv)
+		require.NoError(t, err)
+		assert.Contains(t, out, "# HTTP listen port\n# Type: int, validation: required,min=1,max=65535\nAPP_PORT=8080\n")
+		assert.Contains(t, out, "# Type: string\nAPP_DSN=\n")
+		assert.Contains(t, out, "APP_GREETING=\"hello # world\"\n")
+		assert.Contains(t, out, "APP_UPSTREAMS_<n>_WEIGHT=1\n")
+	})
+
+	t.Run("JSON Schema", func(t *testing.T) {
+		out, err := b.Docs(DocJSONSchema)
+		require.NoError(t, err)
+
+		var schema map[string]any
+		require.NoError(t, json.Unmarshal([]byte(out), &schema))
+		assert.Equal(t, "*confbuilder.DocsConfig", schema["title"])
+		assert.Equal(t, []any{"port"}, schema["required"])
+
+		props := schema["properties"].(map[string]any)
+		assert.Equal(t, map[string]any{
+			"type": "integer", "minimum": 1.0, "maximum": 65535.0, "default": 8080.0, "description": "HTTP listen port",
+		}, props["port"])
+		assert.Equal(t, map[string]any{
+			"type": []any{"string", "integer"}, "default": "30s", "description": "Request timeout",
+		}, props["timeout"])
+		assert.Equal(t, []any{"fast", "safe"}, props["mode"].(map[string]any)["enum"])
+		assert.NotContains(t, props["dsn"], "default")
+		assert.Equal(t, map[string]any{"type": "string"}, props["internal"])
+
+		database := props["database"].(map[string]any)
+		assert.Equal(t, []any{"host"}, database["required"])
+
+		items := props["upstreams"].(map[string]any)["items"].(map[string]any)
+		assert.Equal(t, []any{"url"}, items["required"])
+		assert.Equal(t, 1.0, items["properties"].(map[string]any)["weight"].(map[string]any)["default"])
+
+		assert.Equal(t, map[string]any{"type": "object", "additionalProperties": map[string]any{"type": "boolean"}}, props["labels"])
+	})
+
+	t.Run("Unsupported format", func(t *testing.T) {
+		_, err := b.Docs("html")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), `unsupported docs format "html"`)
+	})
+}
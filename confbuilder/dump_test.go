@@ -0,0 +1,159 @@
+package confbuilder
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// SecretConfig mixes secret and plain fields for redaction tests
+type SecretConfig struct {
+	Name     string         `json:"name" env:"NAME"`
+	Timeout  time.Duration  `json:"timeout" env:"TIMEOUT"`
+	DSN      string         `json:"dsn" env:"DSN" secret:"true"`
+	APIKeys  []string       `json:"api_keys" env:"API_KEYS" secret:"true"`
+	PIN      int            `json:"pin" env:"PIN" secret:"true"`
+	Database DatabaseConfig `json:"database" env:"DB"`
+	Token    string         `json:"token" secret:"true"`
+}
+
+func newSecretConfig() *SecretConfig {
+	return &SecretConfig{
+		Name:    "svc",
+		Timeout: 3 * time.Second,
+		DSN:     "postgres://app:s3cr3t@db:5432/fulcrum?sslmode=disable",
+		APIKeys: []string{"key-1", "key-2"},
+		PIN:     1234,
+		Database: DatabaseConfig{
+			Host:     "localhost",
+			Port:     5432,
+			Password: "not-tagged",
+		},
+		Token: "tok",
+	}
+}
+
+func TestGenericBuilder_DumpJSON(t *testing.T) {
+	cfg := newSecretConfig()
+	out, err := New(cfg).Dump(cfg, DumpJSON)
+	require.NoError(t, err)
+
+	var dumped map[string]any
+	require.NoError(t, json.Unmarshal([]byte(out), &dumped))
+	assert.Equal(t, "svc", dumped["name"])
+	assert.Equal(t, "postgres://app:xxxxx@db:5432/fulcrum?sslmode=disable", dumped["dsn"])
+	assert.Equal(t, []any{"xxxxx", "xxxxx"}, dumped["api_keys"])
+	assert.Equal(t, float64(0), dumped["pin"])
+	assert.Equal(t, "xxxxx", dumped["token"])
+	assert.Equal(t, "localhost", dumped["database"].(map[string]any)["host"])
+
+	// The dumped configuration must not be modified
+	assert.Equal(t, "postgres://app:s3cr3t@db:5432/fulcrum?sslmode=disable", cfg.DSN)
+	assert.Equal(t, []string{"key-1", "key-2"}, cfg.APIKeys)
+	assert.Equal(t, 1234, cfg.PIN)
+}
+
+func TestGenericBuilder_DumpText(t *testing.T) {
+	cfg := newSecretConfig()
+	out, err := New(cfg).Dump(cfg, DumpText)
+	require.NoError(t, err)
+
+	assert.Contains(t, out, "Name = svc\n")
+	assert.Contains(t, out, "Timeout = 3s\n")
+	assert.Contains(t, out, "DSN = postgres://app:xxxxx@db:5432/fulcrum?sslmode=disable\n")
+	assert.Contains(t, out, "APIKeys = xxxxx,xxxxx\n")
+	assert.Contains(t, out, "Database.Port = 5432\n")
+	assert.NotContains(t, out, "s3cr3t")
+	assert.NotContains(t, out, "tok\n")
+}
+
+func TestGenericBuilder_DumpValueType(t *testing.T) {
+	cfg := *newSecretConfig()
+	out, err := New(cfg).Dump(cfg, DumpJSON)
+	require.NoError(t, err)
+	assert.NotContains(t, out, "s3cr3t")
+	assert.Contains(t, out, `"token": "xxxxx"`)
+
+	out, err = New(cfg).Dump(cfg, DumpText)
+	require.NoError(t, err)
+	assert.NotContains(t, out, "s3cr3t")
+	assert.Equal(t, "tok", cfg.Token)
+}
+
+// SecretUpstreams holds secrets inside collections of structs
+type SecretUpstreams struct {
+	Upstreams []SecretUpstream           `json:"upstreams" env:"UPSTREAMS"`
+	Backups   []*SecretUpstream          `json:"backups" env:"BACKUPS"`
+	Tenants   map[string]SecretUpstream  `json:"tenants" env:"TENANTS"`
+	Mirrors   map[string]*SecretUpstream `json:"mirrors" env:"MIRRORS"`
+}
+
+type SecretUpstream struct {
+	URL   string `json:"url" env:"URL"`
+	Token string `json:"token" env:"TOKEN" secret:"true"`
+}
+
+func TestGenericBuilder_DumpCollections(t *testing.T) {
+	cfg := &SecretUpstreams{
+		Upstreams: []SecretUpstream{{URL: "http://a.internal", Token: "UPSECRET"}},
+		Backups:   []*SecretUpstream{{URL: "http://b.internal", Token: "BACKUPSECRET"}, nil},
+		Tenants:   map[string]SecretUpstream{"acme": {URL: "http://acme.internal", Token: "TENANTSECRET"}},
+		Mirrors:   map[string]*SecretUpstream{"eu": {URL: "http://eu.internal", Token: "MIRRORSECRET"}},
+	}
+
+	for _, format := range []DumpFormat{DumpJSON, DumpText} {
+		t.Run(string(format), func(t *testing.T) {
+			out, err := New(cfg).Dump(cfg, format)
+			require.NoError(t, err)
+			assert.Contains(t, out, "http://a.internal")
+			assert.Contains(t, out, "http://acme.internal")
+			for _, secret := range []string{"UPSECRET", "BACKUPSECRET", "TENANTSECRET", "MIRRORSECRET"} {
+				assert.NotContains(t, out, secret)
+			}
+		})
+	}
+
+	// The dumped configuration must not be modified
+	assert.Equal(t, "UPSECRET", cfg.Upstreams[0].Token)
+	assert.Equal(t, "BACKUPSECRET", cfg.Backups[0].Token)
+	assert.Equal(t, "TENANTSECRET", cfg.Tenants["acme"].Token)
+	assert.Equal(t, "MIRRORSECRET", cfg.Mirrors["eu"].Token)
+}
+
+func TestGenericBuilder_DumpErrors(t *testing.T) {
+	cfg := newSecretConfig()
+	_, err := New(cfg).Dump(cfg, "xml")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `unsupported dump format "xml"`)
+
+	_, err = New(cfg).Dump(nil, DumpJSON)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "nil pointer")
+}
+
+func TestRedactSecret(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    string
+		expected string
+	}{
+		{"empty", "", ""},
+		{"plain secret", "hunter2", "xxxxx"},
+		{"url dsn", "postgres://app:s3cr3t@db:5432/fulcrum", "postgres://app:xxxxx@db:5432/fulcrum"},
+		{"url dsn without password", "postgres://app@db:5432/fulcrum", "xxxxx"},
+		{"url with secret path", "https://hooks.slack.com/services/T0/B0/SECRETTOKEN", "xxxxx"},
+		{"url dsn with password param", "postgres://db/fulcrum?password=s3cr3t&user=app", "postgres://db/fulcrum?password=xxxxx&user=app"},
+		{"key value dsn", "host=db user=app password=s3cr3t dbname=fulcrum", "host=db user=app password=xxxxx dbname=fulcrum"},
+		{"key value dsn quoted", "host=db password='s3 cr3t' dbname=fulcrum", "host=db password=xxxxx dbname=fulcrum"},
+		{"mysql dsn", "app:s3cr3t@tcp(db:3306)/fulcrum", "app:xxxxx@tcp(db:3306)/fulcrum"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, redactSecret(tt.value))
+		})
+	}
+}
@@ -0,0 +1,222 @@
+package confbuilder
+
+import (
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/joho/godotenv"
+)
+
+// EnvSource provides the env variables read by the builder
+type EnvSource interface {
+	// Lookup returns the value of the variable name and whether it is set
+	Lookup(name string) (string, bool)
+	// Keys returns the names of every variable
+	Keys() []string
+}
+
+// OSEnv reads the environment of the process, the default source
+type OSEnv struct{}
+
+// Lookup implements EnvSource
+func (OSEnv) Lookup(name string) (string, bool) {
+	return os.LookupEnv(name)
+}
+
+// Keys implements EnvSource
+func (OSEnv) Keys() []string {
+	environ := os.Environ()
+	keys := make([]string, 0, len(environ))
+	for _, kv := range environ {
+		if name, _, ok := strings.Cut(kv, "="); ok {
+			keys = append(keys, name)
+		}
+	}
+	return keys
+}
+
+// MapEnv holds env variables in memory, e.g. for tests or isolated builders
+type MapEnv map[string]string
+
+// Lookup implements EnvSource
+func (m MapEnv) Lookup(name string) (string, bool) {
+	value, ok := m[name]
+	return value, ok
+}
+
+// Keys implements EnvSource
+func (m MapEnv) Keys() []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// ReadDotenv parses .env files without touching the process environment. A variable defined
+// in several files keeps its value from the first one, as when loading them with godotenv
+func ReadDotenv(files ...string) (MapEnv, error) {
+	env := MapEnv{}
+	for _, file := range files {
+		values, err := godotenv.Read(file)
+		if err != nil {
+			return nil, err
+		}
+		for key, value := range values {
+			if _, ok := env[key]; !ok {
+				env[key] = value
+			}
+		}
+	}
+	return env, nil
+}
+
+// EnvPrecedence decides whether the process environment or .env files win when both set a variable
+type EnvPrecedence int
+
+const (
+	// ProcessEnvWins keeps variables of the env source, .env files only fill the missing ones
+	ProcessEnvWins EnvPrecedence = iota
+	// EnvFileWins lets .env files override variables of the env source
+	EnvFileWins
+)
+
+// EnvFileOrder decides which .env file wins when several of them set a variable
+type EnvFileOrder int
+
+const (
+	// NearestFileWins prefers the file closest to the working directory
+	NearestFileWins EnvFileOrder = iota
+	// FarthestFileWins prefers the file closest to the search root, e.g. a project wide file
+	// overriding the ones of sub directories
+	FarthestFileWins
+)
+
+// envFileSearch configures how .env files are discovered and layered
+type envFileSearch struct {
+	names      []string // File names tried in every directory, the first one winning within a directory
+	root       string   // Directory above which no file is searched, empty to search up to the filesystem root
+	markers    []string // Entries marking a project root where the search stops, e.g. go.mod or .git
+	order      EnvFileOrder
+	precedence EnvPrecedence
+}
+
+// discover returns, nearest first, the .env files found in each directory from the working
+// directory up to the search root or the first directory holding a root marker
+func (s envFileSearch) discover() ([][]string, error) {
+	if len(s.names) == 0 {
+		return nil, nil
+	}
+
+	dir, err := os.Getwd()
+	if err != nil {
+		return nil, err
+	}
+	root := ""
+	if s.root != "" {
+		if root, err = filepath.Abs(s.root); err != nil {
+			return nil, err
+		}
+	}
+
+	// A working directory outside of the search root has no .env file to load
+	if !isWithin(dir, root) {
+		return nil, nil
+	}
+
+	var found [][]string
+	for {
+		var files []string
+		for _, name := range s.names {
+			path := filepath.Join(dir, name)
+			if info, err := os.Stat(path); err == nil && !info.IsDir() {
+				files = append(files, path)
+			}
+		}
+		if len(files) > 0 {
+			found = append(found, files)
+		}
+
+		// Stop at the search root, at a project root or at the filesystem root
+		parent := filepath.Dir(dir)
+		if dir == root || !isWithin(dir, root) || hasMarker(dir, s.markers) || parent == dir {
+			break
+		}
+		dir = parent
+	}
+	return found, nil
+}
+
+// isWithin reports whether dir is root or one of its sub directories, any directory is within
+// an empty root
+func isWithin(dir, root string) bool {
+	if root == "" {
+		return true
+	}
+	rel, err := filepath.Rel(root, dir)
+	return err == nil && filepath.IsLocal(rel)
+}
+
+// hasMarker reports whether dir holds one of markers
+func hasMarker(dir string, markers []string) bool {
+	for _, marker := range markers {
+		if _, err := os.Stat(filepath.Join(dir, marker)); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// loadEnvFromAncestors discovers the .env files described by search and returns the files it
+// read, the winning one first, together with the variables they provide that the layering keeps, mapped
+// to the file that provided them. The process environment is left untouched
+func loadEnvFromAncestors(base EnvSource, search envFileSearch) ([]string, MapEnv, map[string]string, error) {
+	dirs, err := search.discover()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	// Files are applied from the winning one so that the first value set is kept
+	ordered := dirs
+	if search.order == FarthestFileWins {
+		ordered = make([][]string, len(dirs))
+		for i, files := range dirs {
+			ordered[len(dirs)-1-i] = files
+		}
+	}
+
+	var loaded []string
+	env := MapEnv{}
+	keys := map[string]string{}
+	for _, files := range ordered {
+		for _, envPath := range files {
+			values, err := godotenv.Read(envPath)
+			if err != nil {
+				continue
+			}
+			slog.Info("Loading .env file", "file", envPath)
+			loaded = append(loaded, envPath)
+
+			for key, value := range values {
+				if _, set := base.Lookup(key); set && search.precedence == ProcessEnvWins {
+					continue
+				}
+				if _, set := env[key]; set {
+					continue
+				}
+				env[key] = value
+				keys[key] = envPath
+			}
+		}
+	}
+
+	if len(search.names) > 0 && len(loaded) == 0 {
+		slog.Info("No .env files found in ancestor directories")
+	}
+
+	return loaded, env, keys, nil
+}
@@ -0,0 +1,177 @@
+package confbuilder
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenericBuilder_EnvSource(t *testing.T) {
+	tests := []struct {
+		name string
+		env  MapEnv
+		port int
+	}{
+		{name: "First", env: MapEnv{"ISO_PORT": "7001", "ISO_DB_PASSWORD_FILE": ""}, port: 7001},
+		{name: "Second", env: MapEnv{"ISO_PORT": "7002"}, port: 7002},
+		{name: "Empty", env: MapEnv{}, port: 8080},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			cfg, err := New(newTestConfig()).EnvPrefix("ISO_").Env(tt.env).Build()
+			require.NoError(t, err)
+			assert.Equal(t, tt.port, cfg.Port)
+		})
+	}
+}
+
+func TestGenericBuilder_DotenvIsolation(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, ".env.isolated", "ISO_PORT=7070\nISO_APP_NAME=dotenv-app\n")
+
+	originalWD, err := os.Getwd()
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(dir))
+	t.Cleanup(func() { os.Chdir(originalWD) })
+
+	// The injected source wins over .env files, which fill the remaining variables
+	cfg, report, err := New(newTestConfig()).
+		EnvPrefix("ISO_").
+		Env(MapEnv{"ISO_PORT": "9090"}).
+		EnvFiles(".env.isolated").
+		BuildWithReport()
+	require.NoError(t, err)
+	assert.Equal(t, 9090, cfg.Port)
+	assert.Equal(t, "dotenv-app", cfg.AppName)
+	assert.Equal(t, Origin{Source: SourceEnvFile, Key: "ISO_APP_NAME", File: filepath.Join(dir, ".env.isolated")}, report.Provenance["AppName"])
+
+	// Nothing leaks into the process environment
+	_, set := os.LookupEnv("ISO_APP_NAME")
+	assert.False(t, set)
+}
+
+func TestReadDotenv(t *testing.T) {
+	dir := t.TempDir()
+	first := writeFile(t, dir, "first.env", "A=1\nB=first\n")
+	second := writeFile(t, dir, "second.env", "B=second\nC=3\n")
+
+	env, err := ReadDotenv(first, second)
+	require.NoError(t, err)
+	assert.Equal(t, MapEnv{"A": "1", "B": "first", "C": "3"}, env)
+	assert.Equal(t, []string{"A", "B", "C"}, env.Keys())
+
+	_, err = ReadDotenv(filepath.Join(dir, "missing.env"))
+	require.Error(t, err)
+}
+
+func TestOSEnv(t *testing.T) {
+	t.Setenv("ISO_OS_ENV", "set")
+
+	value, ok := OSEnv{}.Lookup("ISO_OS_ENV")
+	assert.True(t, ok)
+	assert.Equal(t, "set", value)
+	assert.Contains(t, OSEnv{}.Keys(), "ISO_OS_ENV")
+}
+
+type DiscoveryConfig struct {
+	A string `env:"A"`
+	B string `env:"B"`
+	C string `env:"C"`
+}
+
+func TestGenericBuilder_EnvFileDiscovery(t *testing.T) {
+	top := t.TempDir()
+	project := filepath.Join(top, "project")
+	sub := filepath.Join(project, "sub")
+	require.NoError(t, os.MkdirAll(sub, 0o755))
+
+	topFile := writeFile(t, top, ".env.discovery", "DSC_A=top\nDSC_B=top\n")
+	projectFile := writeFile(t, project, ".env.discovery", "DSC_A=project\nDSC_C=project\n")
+	subFile := writeFile(t, sub, ".env.discovery", "DSC_A=sub\n")
+	writeFile(t, project, "go.mod", "module example\n")
+	elsewhere := t.TempDir()
+
+	originalWD, err := os.Getwd()
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(sub))
+	t.Cleanup(func() { os.Chdir(originalWD) })
+
+	tests := []struct {
+		name      string
+		configure func(*Builder[*DiscoveryConfig])
+		want      DiscoveryConfig
+		wantFiles []string
+	}{
+		{
+			name:      "Nearest file wins by default",
+			configure: func(*Builder[*DiscoveryConfig]) {},
+			want:      DiscoveryConfig{A: "sub", B: "top", C: "project"},
+			wantFiles: []string{subFile, projectFile, topFile},
+		},
+		{
+			name: "Root marker stops the search",
+			configure: func(b *Builder[*DiscoveryConfig]) {
+				b.EnvFileRootMarkers(".git", "go.mod")
+			},
+			want:      DiscoveryConfig{A: "sub", C: "project"},
+			wantFiles: []string{subFile, projectFile},
+		},
+		{
+			name: "Search root stops the search",
+			configure: func(b *Builder[*DiscoveryConfig]) {
+				b.EnvFileSearchRoot(sub)
+			},
+			want:      DiscoveryConfig{A: "sub"},
+			wantFiles: []string{subFile},
+		},
+		{
+			name: "Working directory outside the search root",
+			configure: func(b *Builder[*DiscoveryConfig]) {
+				b.EnvFileSearchRoot(elsewhere)
+			},
+			want: DiscoveryConfig{},
+		},
+		{
+			name: "Farthest file wins",
+			configure: func(b *Builder[*DiscoveryConfig]) {
+				b.EnvFileOrder(FarthestFileWins)
+			},
+			want:      DiscoveryConfig{A: "top", B: "top", C: "project"},
+			wantFiles: []string{topFile, projectFile, subFile},
+		},
+		{
+			name: "Process env wins by default",
+			configure: func(b *Builder[*DiscoveryConfig]) {
+				b.Env(MapEnv{"DSC_A": "process"})
+			},
+			want:      DiscoveryConfig{A: "process", B: "top", C: "project"},
+			wantFiles: []string{subFile, projectFile, topFile},
+		},
+		{
+			name: "Env files override the process env",
+			configure: func(b *Builder[*DiscoveryConfig]) {
+				b.Env(MapEnv{"DSC_A": "process", "DSC_B": "process"}).EnvPrecedence(EnvFileWins)
+			},
+			want:      DiscoveryConfig{A: "sub", B: "top", C: "project"},
+			wantFiles: []string{subFile, projectFile, topFile},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := New(&DiscoveryConfig{}).EnvPrefix("DSC_").EnvFiles(".env.discovery").Env(MapEnv{})
+			tt.configure(b)
+
+			cfg, report, err := b.BuildWithReport()
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, *cfg)
+			assert.Equal(t, tt.wantFiles, report.EnvFiles)
+		})
+	}
+}
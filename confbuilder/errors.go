@@ -0,0 +1,245 @@
+package confbuilder
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// FieldError describes one invalid configuration field
+type FieldError struct {
+	Path    string     // Go field path, e.g. Database.Port
+	EnvVar  string     // Env variable bound to the field, empty when the field has no env tag
+	Source  SourceKind // Layer that provided the invalid value
+	Value   string     // Raw value as provided by the source, masked for secret fields
+	Message string     // Human readable problem, e.g. "DB_PORT must be between 1 and 65535"
+	Err     error      // Underlying parse or validation error
+}
+
+// Error implements the error interface
+func (e FieldError) Error() string {
+	return e.Message
+}
+
+// Unwrap returns the underlying error
+func (e FieldError) Unwrap() error {
+	return e.Err
+}
+
+// ConfigError collects every invalid field found while building a configuration so that all
+// of them can be fixed at once
+type ConfigError struct {
+	Fields []FieldError
+}
+
+// Error implements the error interface
+func (e *ConfigError) Error() string {
+	messages := make([]string, len(e.Fields))
+	for i, f := range e.Fields {
+		messages[i] = f.Message
+	}
+	return "invalid configuration: " + strings.Join(messages, "; ")
+}
+
+// Unwrap returns the errors of every field, allowing errors.Is and errors.As on them
+func (e *ConfigError) Unwrap() []error {
+	errs := make([]error, len(e.Fields))
+	for i, f := range e.Fields {
+		errs[i] = f
+	}
+	return errs
+}
+
+// add appends a field error unless one is already recorded for the same path
+func (e *ConfigError) add(fe FieldError) {
+	for _, existing := range e.Fields {
+		if existing.Path == fe.Path {
+			return
+		}
+	}
+	e.Fields = append(e.Fields, fe)
+}
+
+// errOrNil returns e as an error only when it holds at least one field
+func (e *ConfigError) errOrNil() error {
+	if len(e.Fields) == 0 {
+		return nil
+	}
+	return e
+}
+
+// displayValue renders a raw value for error reports, masking secrets
+func displayValue(f fieldInfo, raw string) string {
+	if isSecret(f.field) {
+		return redactSecret(raw)
+	}
+	return raw
+}
+
+// addValidationErrors converts validator errors into field errors with readable messages
+func addValidationErrors(cfgErr *ConfigError, err error, target any, prefix string, naming envNaming, messages map[string]string, p Provenance) error {
+	var validationErrs validator.ValidationErrors
+	if !errors.As(err, &validationErrs) {
+		return err
+	}
+
+	fields := map[string]fieldInfo{}
+	if err := walkFields(target, naming, func(f fieldInfo) error {
+		fields[f.path] = f
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	for _, fe := range validationErrs {
+		// Drop the root struct name from the namespace, e.g. TestConfig.Database.Port
+		path := fe.StructNamespace()
+		if _, rest, ok := strings.Cut(path, "."); ok {
+			path = rest
+		}
+
+		// Dive errors such as Tags[0] belong to the Tags field
+		basePath, _, _ := strings.Cut(path, "[")
+		info := fields[basePath]
+
+		name := path
+		envVar := ""
+		origin, ok := p[path]
+		switch {
+		case ok && (origin.Source == SourceEnv || origin.Source == SourceEnvFile):
+			// Elements of collections are named after their indexed variable, e.g. UPSTREAMS_1_URL
+			envVar, name = origin.Key, origin.Key
+		case info.envKey != "":
+			envVar = prefix + info.envKey
+			name = envVar + strings.TrimPrefix(path, basePath)
+		}
+		if !ok {
+			origin = p[basePath]
+		}
+
+		var raw string
+		if v := fe.Value(); v != nil {
+			raw = formatValue(reflect.ValueOf(v))
+		}
+
+		cfgErr.add(FieldError{
+			Path:    path,
+			EnvVar:  envVar,
+			Source:  origin.Source,
+			Value:   displayValue(info, raw),
+			Message: validationMessage(name, fe, info, messages),
+			Err:     fe,
+		})
+	}
+	return nil
+}
+
+// validationMessage renders a validator error as a sentence naming the field, using the
+// template registered for the rule when there is one
+func validationMessage(name string, fe validator.FieldError, info fieldInfo, messages map[string]string) string {
+	param := fe.Param()
+	if message, ok := messages[fe.Tag()]; ok {
+		return strings.NewReplacer("{field}", name, "{param}", param).Replace(message)
+	}
+	kind := fe.Kind()
+	isNumber := kind >= reflect.Int && kind <= reflect.Float64
+
+	// Report min and max together as a range when both are declared
+	if fe.Tag() == "min" || fe.Tag() == "max" {
+		if minimum, maximum, ok := rangeRule(info.field.Tag.Get("validate")); ok {
+			switch {
+			case isNumber:
+				return fmt.Sprintf("%s must be between %s and %s", name, minimum, maximum)
+			case kind == reflect.String:
+				return fmt.Sprintf("%s must be between %s and %s characters long", name, minimum, maximum)
+			case kind == reflect.Slice || kind == reflect.Map:
+				return fmt.Sprintf("%s must contain between %s and %s items", name, minimum, maximum)
+			}
+		}
+	}
+
+	switch fe.Tag() {
+	case "required":
+		return fmt.Sprintf("%s is required", name)
+	case "required_if":
+		// Parameters are field and value pairs, e.g. "Profile prod"
+		params := strings.Fields(param)
+		var conditions []string
+		for i := 0; i+1 < len(params); i += 2 {
+			conditions = append(conditions, params[i]+" is "+params[i+1])
+		}
+		return fmt.Sprintf("%s is required when %s", name, strings.Join(conditions, " and "))
+	case "min", "gte":
+		switch kind {
+		case reflect.String:
+			return fmt.Sprintf("%s must be at least %s characters long", name, param)
+		case reflect.Slice, reflect.Map:
+			return fmt.Sprintf("%s must contain at least %s items", name, param)
+		}
+		return fmt.Sprintf("%s must be at least %s", name, param)
+	case "max", "lte":
+		switch kind {
+		case reflect.String:
+			return fmt.Sprintf("%s must be at most %s characters long", name, param)
+		case reflect.Slice, reflect.Map:
+			return fmt.Sprintf("%s must contain at most %s items", name, param)
+		}
+		return fmt.Sprintf("%s must be at most %s", name, param)
+	case "gt":
+		return fmt.Sprintf("%s must be greater than %s", name, param)
+	case "lt":
+		return fmt.Sprintf("%s must be less than %s", name, param)
+	case "len":
+		return fmt.Sprintf("%s must have a length of %s", name, param)
+	case "oneof":
+		return fmt.Sprintf("%s must be one of: %s", name, strings.Join(strings.Fields(param), ", "))
+	case "alphanum":
+		return fmt.Sprintf("%s must contain only letters and digits", name)
+	case "ip", "ipv4", "ipv6":
+		return fmt.Sprintf("%s must be a valid IP address", name)
+	case "hostname", "hostname_rfc1123":
+		return fmt.Sprintf("%s must be a valid hostname", name)
+	case "url":
+		return fmt.Sprintf("%s must be a valid URL", name)
+	case "email":
+		return fmt.Sprintf("%s must be a valid email address", name)
+	case "semver":
+		return fmt.Sprintf("%s must be a valid semantic version", name)
+	case "dsn":
+		return fmt.Sprintf("%s must be a valid DSN", name)
+	case "duration_between":
+		if minimum, maximum, ok := strings.Cut(param, " "); ok {
+			return fmt.Sprintf("%s must be between %s and %s", name, minimum, strings.TrimSpace(maximum))
+		}
+	case "hostport":
+		return fmt.Sprintf("%s must be a host:port address", name)
+	case "existing_file":
+		return fmt.Sprintf("%s must be an existing file", name)
+	case "existing_dir":
+		return fmt.Sprintf("%s must be an existing directory", name)
+	}
+
+	if param != "" {
+		return fmt.Sprintf("%s must satisfy %s=%s", name, fe.Tag(), param)
+	}
+	return fmt.Sprintf("%s must satisfy %s", name, fe.Tag())
+}
+
+// rangeRule returns the min and max parameters of a validate tag when both are present
+// before any dive
+func rangeRule(rules string) (string, string, bool) {
+	rules, _, _ = strings.Cut(rules, ",dive")
+	var minimum, maximum string
+	for _, rule := range strings.Split(rules, ",") {
+		if v, ok := strings.CutPrefix(rule, "min="); ok {
+			minimum = v
+		}
+		if v, ok := strings.CutPrefix(rule, "max="); ok {
+			maximum = v
+		}
+	}
+	return minimum, maximum, minimum != "" && maximum != ""
+}
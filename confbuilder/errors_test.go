@@ -0,0 +1,93 @@
+package confbuilder
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenericBuilder_ConfigError(t *testing.T) {
+	setEnvVars(t, map[string]string{
+		"TEST_PORT":        "not-an-int",
+		"TEST_TIMEOUT":     "forever",
+		"TEST_DB_PORT":     "70000",
+		"TEST_ENVIRONMENT": "qa",
+	})
+
+	defaultCfg := newTestConfig()
+	defaultCfg.AppName = ""
+
+	_, err := New(defaultCfg).EnvPrefix("TEST_").Build()
+	require.Error(t, err)
+
+	var cfgErr *ConfigError
+	require.True(t, errors.As(err, &cfgErr))
+
+	byPath := map[string]FieldError{}
+	for _, fe := range cfgErr.Fields {
+		byPath[fe.Path] = fe
+	}
+	require.Len(t, byPath, 5)
+
+	// Parse errors keep the raw value and the env variable
+	port := byPath["Port"]
+	assert.Equal(t, "TEST_PORT", port.EnvVar)
+	assert.Equal(t, SourceEnv, port.Source)
+	assert.Equal(t, "not-an-int", port.Value)
+	assert.Contains(t, port.Message, "invalid integer value for TEST_PORT")
+	var numErr *strconv.NumError
+	assert.True(t, errors.As(err, &numErr))
+
+	assert.Contains(t, byPath["Timeout"].Message, "invalid duration value for TEST_TIMEOUT")
+
+	// Validation errors get readable messages
+	dbPort := byPath["Database.Port"]
+	assert.Equal(t, "TEST_DB_PORT must be between 1 and 65535", dbPort.Message)
+	assert.Equal(t, "TEST_DB_PORT", dbPort.EnvVar)
+	assert.Equal(t, SourceEnv, dbPort.Source)
+	assert.Equal(t, "70000", dbPort.Value)
+
+	assert.Equal(t, "TEST_ENVIRONMENT must be one of: development, staging, production", byPath["Environment"].Message)
+
+	appName := byPath["AppName"]
+	assert.Equal(t, "TEST_APP_NAME is required", appName.Message)
+	assert.Equal(t, SourceDefault, appName.Source)
+
+	assert.Contains(t, err.Error(), "invalid configuration: ")
+	assert.Contains(t, err.Error(), "TEST_APP_NAME is required; ")
+}
+
+func TestGenericBuilder_ConfigErrorMessages(t *testing.T) {
+	type Config struct {
+		Name     string   `env:"NAME" validate:"min=3,max=5"`
+		Hosts    []string `env:"HOSTS" validate:"min=1,dive,hostname_rfc1123"`
+		Retries  int      `validate:"gte=1"`
+		Password string   `env:"PASSWORD" validate:"min=12" secret:"true"`
+	}
+
+	setEnvVars(t, map[string]string{
+		"APP_NAME":     "toolongname",
+		"APP_HOSTS":    "ok.example.com,bad_host!",
+		"APP_PASSWORD": "short",
+	})
+
+	_, err := New(&Config{}).EnvPrefix("APP_").Build()
+	require.Error(t, err)
+
+	var cfgErr *ConfigError
+	require.True(t, errors.As(err, &cfgErr))
+
+	messages := map[string]FieldError{}
+	for _, fe := range cfgErr.Fields {
+		messages[fe.Path] = fe
+	}
+	assert.Equal(t, "APP_NAME must be between 3 and 5 characters long", messages["Name"].Message)
+	assert.Equal(t, "APP_HOSTS[1] must be a valid hostname", messages["Hosts[1]"].Message)
+	assert.Equal(t, "Retries must be at least 1", messages["Retries"].Message)
+	assert.Equal(t, "", messages["Retries"].EnvVar)
+	assert.Equal(t, "APP_PASSWORD must be at least 12 characters long", messages["Password"].Message)
+	assert.Equal(t, "xxxxx", messages["Password"].Value) // Secrets are masked
+}
@@ -0,0 +1,225 @@
+package confbuilder
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"unicode"
+)
+
+// fieldInfo describes a settable leaf field reached while walking a configuration struct
+type fieldInfo struct {
+	path     string   // Go field path, e.g. Database.Port
+	envKey   string   // Env variable name without prefix, e.g. DB_PORT, empty when the field has no tag
+	jsonPath []string // Keys locating the field in a config file, nil when excluded with json:"-"
+	field    reflect.StructField
+	value    reflect.Value
+	ensure   func() // Attaches the nil pointer structs enclosing the field, call after setting value
+}
+
+// NamingStrategy derives the env name of a field without env tag from its Go name
+type NamingStrategy func(fieldName string) string
+
+// SnakeUpper derives upper snake case names, e.g. MaxIdleConns becomes MAX_IDLE_CONNS and
+// HTTPPort becomes HTTP_PORT
+func SnakeUpper(fieldName string) string {
+	runes := []rune(fieldName)
+	var sb strings.Builder
+	for i, r := range runes {
+		if i > 0 && unicode.IsUpper(r) {
+			prev := runes[i-1]
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			// Split before a new word, keeping acronyms such as HTTP together
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && nextLower) {
+				sb.WriteByte('_')
+			}
+		}
+		sb.WriteRune(unicode.ToUpper(r))
+	}
+	return sb.String()
+}
+
+// envNaming binds fields to env variable names
+type envNaming struct {
+	tag      string         // Struct tag holding explicit names
+	auto     NamingStrategy // Derives the names of untagged fields, nil to bind tagged fields only
+	excluded bool           // Set below struct fields excluded with the "-" tag
+	decoders decoders       // Struct types with a registered decoder are leaves, not walked
+	opaque   bool           // Struct types without exported fields are leaves, e.g. to compare them
+}
+
+// name returns the env name of field relative to its parent struct, empty when the field is
+// not bound. Explicit tags win over derived names
+func (n envNaming) name(field reflect.StructField) string {
+	if n.excluded {
+		return ""
+	}
+	name, _, _ := strings.Cut(field.Tag.Get(n.tag), ",")
+	switch {
+	case name == "-":
+		return ""
+	case name == "" && n.auto != nil && !field.Anonymous:
+		return n.auto(field.Name)
+	}
+	return name
+}
+
+// child returns the naming used for the fields of the nested struct field
+func (n envNaming) child(field reflect.StructField) envNaming {
+	if field.Tag.Get(n.tag) == "-" {
+		n.excluded = true
+	}
+	return n
+}
+
+// isLeaf reports whether values of struct type t are decoded as a whole rather than walked
+// field by field, either by a registered decoder or by their own unmarshaling methods
+func (n envNaming) isLeaf(t reflect.Type) bool {
+	if _, ok := n.decoders[t]; ok {
+		return true
+	}
+	if n.opaque && !hasExportedFields(t) {
+		return true
+	}
+	return isLeafType(t)
+}
+
+// hasExportedFields reports whether struct type t has at least one exported field
+func hasExportedFields(t reflect.Type) bool {
+	for i := 0; i < t.NumField(); i++ {
+		if t.Field(i).IsExported() {
+			return true
+		}
+	}
+	return false
+}
+
+// option reports whether the env tag of field holds option after the name, e.g.
+// `env:"NAME,allowempty"`
+func (n envNaming) option(field reflect.StructField, option string) bool {
+	_, options, _ := strings.Cut(field.Tag.Get(n.tag), ",")
+	for _, o := range strings.Split(options, ",") {
+		if strings.TrimSpace(o) == option {
+			return true
+		}
+	}
+	return false
+}
+
+// walkFields calls fn for every settable leaf field of target, nesting env names the same way
+// as loadEnvToStruct: a struct field name is joined to its children's names with "_"
+func walkFields(target any, naming envNaming, fn func(fieldInfo) error) error {
+	v := reflect.ValueOf(target)
+
+	// Dereference all pointer levels to get to the actual value
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return fmt.Errorf("cannot load environment variables into nil pointer")
+		}
+		v = v.Elem()
+	}
+
+	return walkStruct(v, naming, "", "", []string{}, func() {}, fn)
+}
+
+// walkStruct walks the fields of the struct value v
+func walkStruct(v reflect.Value, naming envNaming, parentEnvPath, parentPath string, parentJSON []string, ensure func(), fn func(fieldInfo) error) error {
+	t := v.Type()
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		fieldValue := v.Field(i)
+
+		// Skip unexported fields
+		if !fieldValue.CanSet() {
+			continue
+		}
+
+		path := joinPath(parentPath, field.Name)
+		envName := naming.name(field)
+		jsonPath := joinJSON(parentJSON, field)
+
+		// Recurse into nested structs, extending the env path when the struct field is tagged
+		if nested, nestedEnsure, ok := nestedStruct(fieldValue, naming, ensure); ok {
+			envPath := parentEnvPath
+			if envName != "" {
+				envPath = joinEnv(parentEnvPath, envName)
+			}
+			if err := walkStruct(nested, naming.child(field), envPath, path, jsonPath, nestedEnsure, fn); err != nil {
+				return fmt.Errorf("error loading sub config field %s: %w", field.Name, err)
+			}
+			continue
+		}
+
+		info := fieldInfo{path: path, jsonPath: jsonPath, field: field, value: fieldValue, ensure: ensure}
+		if envName != "" {
+			info.envKey = joinEnv(parentEnvPath, envName)
+		}
+		if err := fn(info); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// nestedStruct returns the struct to recurse into for a struct or pointer to struct field.
+// A nil pointer is replaced by a detached zero struct, the returned ensure func attaches it
+// to the field once one of its leaves is set
+func nestedStruct(fieldValue reflect.Value, naming envNaming, ensure func()) (reflect.Value, func(), bool) {
+	t := fieldValue.Type()
+	switch {
+	case t.Kind() == reflect.Struct && !naming.isLeaf(t):
+		return fieldValue, ensure, true
+	case t.Kind() == reflect.Ptr && t.Elem().Kind() == reflect.Struct && !naming.isLeaf(t.Elem()):
+		if !fieldValue.IsNil() {
+			return fieldValue.Elem(), ensure, true
+		}
+		detached := reflect.New(t.Elem())
+		return detached.Elem(), func() {
+			if fieldValue.IsNil() {
+				ensure()
+				fieldValue.Set(detached)
+			}
+		}, true
+	}
+	return reflect.Value{}, nil, false
+}
+
+// joinPath appends a field name to a dotted Go field path
+func joinPath(parent, name string) string {
+	if parent == "" {
+		return name
+	}
+	return parent + "." + name
+}
+
+// joinEnv appends an env tag to the env path of its parent struct
+func joinEnv(parent, name string) string {
+	if parent == "" {
+		return name
+	}
+	return parent + "_" + name
+}
+
+// joinJSON appends the json key of field to the key path of its parent struct, following
+// encoding/json rules for ignored fields and untagged embedded structs
+func joinJSON(parent []string, field reflect.StructField) []string {
+	if parent == nil {
+		return nil
+	}
+
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	switch {
+	case name == "-":
+		return nil
+	case name == "" && field.Anonymous:
+		return parent
+	case name == "":
+		name = field.Name
+	}
+
+	path := make([]string, len(parent), len(parent)+1)
+	copy(path, parent)
+	return append(path, name)
+}
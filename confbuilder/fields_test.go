@@ -0,0 +1,80 @@
+package confbuilder
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSnakeUpper(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{input: "Port", want: "PORT"},
+		{input: "MaxIdleConns", want: "MAX_IDLE_CONNS"},
+		{input: "HTTPPort", want: "HTTP_PORT"},
+		{input: "DBHost", want: "DB_HOST"},
+		{input: "URL", want: "URL"},
+		{input: "OAuth2Token", want: "O_AUTH2_TOKEN"},
+		{input: "TLSCertFile", want: "TLS_CERT_FILE"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			assert.Equal(t, tt.want, SnakeUpper(tt.input))
+		})
+	}
+}
+
+type AutoEnvConfig struct {
+	AppName  string
+	Port     int    `env:"LISTEN_PORT"`
+	Internal string `env:"-"`
+	Database AutoEnvDatabase
+	Legacy   AutoEnvDatabase `env:"-"`
+	Cache    AutoEnvCache    `env:"KV"`
+}
+
+type AutoEnvDatabase struct {
+	MaxIdleConns int
+	ConnTimeout  time.Duration
+}
+
+type AutoEnvCache struct {
+	TTL time.Duration
+}
+
+func TestGenericBuilder_AutoEnv(t *testing.T) {
+	setEnvVars(t, map[string]string{
+		"AUTO_APP_NAME":                "auto",
+		"AUTO_LISTEN_PORT":             "8080",
+		"AUTO_PORT":                    "1",
+		"AUTO_INTERNAL":                "leak",
+		"AUTO_DATABASE_MAX_IDLE_CONNS": "12",
+		"AUTO_DATABASE_CONN_TIMEOUT":   "3s",
+		"AUTO_LEGACY_MAX_IDLE_CONNS":   "99",
+		"AUTO_KV_TTL":                  "1m",
+	})
+
+	cfg, err := New(&AutoEnvConfig{}).EnvPrefix("AUTO_").AutoEnv(SnakeUpper).Build()
+	require.NoError(t, err)
+
+	assert.Equal(t, "auto", cfg.AppName)
+	assert.Equal(t, 8080, cfg.Port) // Explicit tags win over derived names
+	assert.Equal(t, "", cfg.Internal)
+	assert.Equal(t, 12, cfg.Database.MaxIdleConns)
+	assert.Equal(t, 3*time.Second, cfg.Database.ConnTimeout)
+	assert.Equal(t, 0, cfg.Legacy.MaxIdleConns) // Excluded structs exclude their fields
+	assert.Equal(t, time.Minute, cfg.Cache.TTL)
+
+	// Without AutoEnv only tagged fields are bound
+	cfg, err = New(&AutoEnvConfig{}).EnvPrefix("AUTO_").Build()
+	require.NoError(t, err)
+	assert.Equal(t, "", cfg.AppName)
+	assert.Equal(t, 8080, cfg.Port)
+	assert.Equal(t, 0, cfg.Database.MaxIdleConns)
+	assert.Equal(t, time.Duration(0), cfg.Cache.TTL)
+}
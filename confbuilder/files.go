@@ -0,0 +1,95 @@
+package confbuilder
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// FileLayer describes a configuration file merged on top of the previous layers
+type FileLayer struct {
+	Path     string
+	Format   Format // Detected from the extension when empty
+	Optional bool   // Missing optional layers are skipped instead of failing the build
+}
+
+// RequiredFile returns a layer that fails the build when the file does not exist
+func RequiredFile(path string) FileLayer {
+	return FileLayer{Path: path}
+}
+
+// OptionalFile returns a layer that is skipped when the file does not exist
+func OptionalFile(path string) FileLayer {
+	return FileLayer{Path: path, Optional: true}
+}
+
+// loadedFile is a parsed configuration file layer
+type loadedFile struct {
+	path   string
+	tree   any
+	source SourceKind // SourceFile or SourceRemote, path holding the URL of remote sources
+}
+
+// parseError reports a value of the file that does not fit its field, prefixed with the file
+// path like syntax errors
+func (f loadedFile) parseError(err error) error {
+	if f.path == "" {
+		return fmt.Errorf("failed to parse config file: %w", err)
+	}
+	return fmt.Errorf("failed to parse config file: %s: %w", f.path, err)
+}
+
+// loadFileLayers reads and parses every existing layer in order
+func loadFileLayers(layers []FileLayer) ([]loadedFile, error) {
+	var loaded []loadedFile
+	for _, layer := range layers {
+		if layer.Path == "" {
+			continue
+		}
+
+		data, err := os.ReadFile(layer.Path)
+		if err != nil {
+			if layer.Optional && errors.Is(err, fs.ErrNotExist) {
+				slog.Info("Skipping optional config file", "file", layer.Path)
+				continue
+			}
+			return nil, fmt.Errorf("failed to read config file: %w", err)
+		}
+
+		tree, err := parseTree(layer.Path, data, layer.Format)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse config file: %w", err)
+		}
+
+		loaded = append(loaded, loadedFile{path: layer.Path, tree: tree, source: SourceFile})
+	}
+	return loaded, nil
+}
+
+// mergeTrees deep merges src onto dst, objects are merged key by key while any other value replaces
+func mergeTrees(dst, src any) any {
+	dstMap, dstOK := dst.(map[string]any)
+	srcMap, srcOK := src.(map[string]any)
+	if !dstOK || !srcOK {
+		if src == nil {
+			return dst
+		}
+		return src
+	}
+
+	for key, srcVal := range srcMap {
+		// Keys are matched case-insensitively like encoding/json does
+		dstKey := key
+		for existing := range dstMap {
+			if strings.EqualFold(existing, key) {
+				dstKey = existing
+				break
+			}
+		}
+		dstMap[dstKey] = mergeTrees(dstMap[dstKey], srcVal)
+	}
+	return dstMap
+}
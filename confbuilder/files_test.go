@@ -0,0 +1,150 @@
+package confbuilder
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeFile writes content into a file named name inside dir and returns its path
+func writeFile(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	return path
+}
+
+func TestGenericBuilder_FileLayers(t *testing.T) {
+	dir := t.TempDir()
+	base := writeFile(t, dir, "base.json", `{
+		"app_name": "base-app",
+		"port": 8081,
+		"tags": ["base"],
+		"database": {"host": "base-db", "port": 5433, "username": "baseuser"}
+	}`)
+	production := writeFile(t, dir, "production.yaml", `
+environment: production
+database:
+  host: prod-db
+`)
+	local := writeFile(t, dir, "local.toml", `
+tags = ["local"]
+
+[database]
+port = 6432
+`)
+
+	cfg, err := New(newTestConfig()).
+		Files(RequiredFile(base), RequiredFile(production), OptionalFile(local)).
+		Build()
+	require.NoError(t, err)
+
+	assert.Equal(t, "base-app", cfg.AppName)           // From base
+	assert.Equal(t, 8081, cfg.Port)                    // From base
+	assert.Equal(t, "production", cfg.Environment)     // From production
+	assert.Equal(t, []string{"local"}, cfg.Tags)       // Slices are replaced by later layers
+	assert.Equal(t, "prod-db", cfg.Database.Host)      // Nested key overridden by production
+	assert.Equal(t, 6432, cfg.Database.Port)           // Nested key overridden by local
+	assert.Equal(t, "baseuser", cfg.Database.Username) // Nested key kept from base
+	assert.Equal(t, "testpass123", cfg.Database.Password)
+}
+
+// LayeredTenants holds maps whose entries are merged across file layers
+type LayeredTenants struct {
+	Tenants map[string]LayeredTenant     `json:"tenants"`
+	Labels  map[string]map[string]string `json:"labels"`
+}
+
+type LayeredTenant struct {
+	URL    string `json:"url"`
+	Token  string `json:"token"`
+	Weight int    `json:"weight"`
+}
+
+func TestGenericBuilder_FileLayersMaps(t *testing.T) {
+	dir := t.TempDir()
+	base := writeFile(t, dir, "base.json", `{
+		"tenants": {"acme": {"url": "a", "token": "t", "weight": 5}, "globex": {"url": "g"}},
+		"labels": {"team": {"name": "core", "owner": "ops"}}
+	}`)
+	prod := writeFile(t, dir, "prod.json", `{
+		"tenants": {"acme": {"token": "u"}},
+		"labels": {"team": {"owner": "sre"}}
+	}`)
+
+	cfg, err := New(&LayeredTenants{}).Env(MapEnv{}).Files(RequiredFile(base), RequiredFile(prod)).Build()
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string]LayeredTenant{
+		"acme":   {URL: "a", Token: "u", Weight: 5},
+		"globex": {URL: "g"},
+	}, cfg.Tenants)
+	assert.Equal(t, map[string]map[string]string{"team": {"name": "core", "owner": "sre"}}, cfg.Labels)
+}
+
+func TestGenericBuilder_FileLayersWithFile(t *testing.T) {
+	dir := t.TempDir()
+	base := writeFile(t, dir, "config.json", `{"app_name": "file-app", "port": 9090}`)
+	override := writeFile(t, dir, "override.json", `{"port": 9191}`)
+
+	cfg, err := New(newTestConfig()).File(&base).Files(RequiredFile(override)).Build()
+	require.NoError(t, err)
+
+	assert.Equal(t, "file-app", cfg.AppName)
+	assert.Equal(t, 9191, cfg.Port)
+}
+
+func TestGenericBuilder_FileLayersMissing(t *testing.T) {
+	dir := t.TempDir()
+	base := writeFile(t, dir, "base.json", `{"app_name": "base-app"}`)
+	missing := filepath.Join(dir, "missing.json")
+
+	t.Run("optional layer is skipped", func(t *testing.T) {
+		cfg, err := New(newTestConfig()).Files(RequiredFile(base), OptionalFile(missing)).Build()
+		require.NoError(t, err)
+		assert.Equal(t, "base-app", cfg.AppName)
+	})
+
+	t.Run("required layer fails", func(t *testing.T) {
+		_, err := New(newTestConfig()).Files(RequiredFile(base), RequiredFile(missing)).Build()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to read config file")
+	})
+
+	t.Run("invalid optional layer still fails", func(t *testing.T) {
+		broken := writeFile(t, dir, "broken.json", `{broken`)
+		_, err := New(newTestConfig()).Files(OptionalFile(broken)).Build()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to parse config file")
+	})
+}
+
+func TestMergeTrees(t *testing.T) {
+	dst := map[string]any{
+		"name": "base",
+		"database": map[string]any{
+			"host": "base-db",
+			"port": 5432,
+		},
+	}
+	src := map[string]any{
+		"Database": map[string]any{"Port": 6432},
+		"extra":    []any{"x"},
+	}
+
+	merged := mergeTrees(dst, src)
+	assert.Equal(t, map[string]any{
+		"name": "base",
+		"database": map[string]any{
+			"host": "base-db",
+			"port": 6432,
+		},
+		"extra": []any{"x"},
+	}, merged)
+
+	assert.Equal(t, "kept", mergeTrees("kept", nil))
+	assert.Equal(t, src, mergeTrees(nil, src))
+}
@@ -0,0 +1,119 @@
+package confbuilder
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+)
+
+// Flags enables the command-line flags source, parsed from args with the highest precedence
+func (b *Builder[T]) Flags(args []string) *Builder[T] {
+	b.flagsEnabled = true
+	b.flagArgs = args
+	return b
+}
+
+// FlagOutput sets where the --help listing and flag errors are written, os.Stderr by default
+func (b *Builder[T]) FlagOutput(w io.Writer) *Builder[T] {
+	b.flagOutput = w
+	return b
+}
+
+// configFlag is a flag.Value that decodes its argument directly into a configuration field
+type configFlag struct {
+	info     fieldInfo
+	name     string
+	decoders decoders
+}
+
+// String returns the current field value, used by the flag package as the default
+func (f *configFlag) String() string {
+	if f == nil || !f.info.value.IsValid() {
+		return ""
+	}
+	return formatValue(f.info.value)
+}
+
+// Set decodes the flag argument into the field
+func (f *configFlag) Set(raw string) error {
+	if err := f.decoders.setFieldValue(f.info.value, "--"+f.name, raw); err != nil {
+		return err
+	}
+	f.info.ensure()
+	return nil
+}
+
+// IsBoolFlag allows boolean fields to be set with a bare --flag
+func (f *configFlag) IsBoolFlag() bool {
+	t := f.info.value.Type()
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t.Kind() == reflect.Bool
+}
+
+// flagName derives the flag name from an env key, e.g. DB_HOST becomes db-host
+func flagName(envKey string) string {
+	return strings.ReplaceAll(strings.ToLower(envKey), "_", "-")
+}
+
+// loadFlagsToStruct registers a flag for every env tagged field of target and parses args into them
+func loadFlagsToStruct(target any, prefix string, naming envNaming, d decoders, args []string, output io.Writer, p Provenance) error {
+	fs := flag.NewFlagSet(filepath.Base(os.Args[0]), flag.ContinueOnError)
+	if output != nil {
+		fs.SetOutput(output)
+	}
+
+	var flags []*configFlag
+	err := walkFields(target, naming, func(f fieldInfo) error {
+		// Struct collections are only set from indexed env variables, see loadIndexed
+		if f.envKey == "" || isStructCollection(f.value.Type()) {
+			return nil
+		}
+		cf := &configFlag{info: f, name: flagName(f.envKey), decoders: d}
+		if existing := fs.Lookup(cf.name); existing != nil {
+			return fmt.Errorf("invalid flag --%s: bound to both %s and %s", cf.name, existing.Value.(*configFlag).info.path, f.path)
+		}
+		fs.Var(cf, cf.name, fmt.Sprintf("sets %s (env %s)", f.path, prefix+f.envKey))
+		flags = append(flags, cf)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	fs.Usage = func() {
+		printFlagUsage(fs, flags)
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	// Only the flags present on the command line override the other sources
+	fs.Visit(func(f *flag.Flag) {
+		cf := f.Value.(*configFlag)
+		p[cf.info.path] = Origin{Source: SourceFlag, Key: "--" + cf.name}
+	})
+	return nil
+}
+
+// printFlagUsage writes the --help listing generated from the configuration fields
+func printFlagUsage(fs *flag.FlagSet, flags []*configFlag) {
+	w := fs.Output()
+	fmt.Fprintf(w, "Usage of %s:\n", fs.Name())
+	for _, cf := range flags {
+		usage := fs.Lookup(cf.name).Usage
+		fmt.Fprintf(w, "  --%s %s\n", cf.name, cf.info.value.Type())
+		// Secret values are never shown, they may come from files or the environment
+		if def := cf.String(); def != "" && !isSecret(cf.info.field) {
+			fmt.Fprintf(w, "    \t%s (default %q)\n", usage, def)
+		} else {
+			fmt.Fprintf(w, "    \t%s\n", usage)
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package confbuilder
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenericBuilder_Flags(t *testing.T) {
+	setEnvVars(t, map[string]string{
+		"TEST_PORT":    "6060",
+		"TEST_DB_HOST": "env-db",
+		"TEST_TAGS":    "env",
+	})
+
+	cfg, err := New(newTestConfig()).
+		EnvPrefix("TEST_").
+		Flags([]string{
+			"--port", "7070",
+			"--db-host=flag-db",
+			"--debug-mode",
+			"--log-level", "warn",
+			"--server-timeout", "15s",
+			"-load-factor", "3.5",
+		}).
+		FlagOutput(&bytes.Buffer{}).
+		Build()
+	require.NoError(t, err)
+
+	assert.Equal(t, 7070, cfg.Port)                        // Flag overrides env
+	assert.Equal(t, "flag-db", cfg.Database.Host)          // Flag overrides nested env
+	assert.True(t, cfg.DebugMode)                          // Bare boolean flag
+	assert.Equal(t, slog.LevelWarn, cfg.LogLevel)          // Decoded like env values
+	assert.Equal(t, 15*time.Second, cfg.Server.Timeout)    // Nested struct flag
+	assert.Equal(t, 3.5, cfg.LoadFactor)                   // Single dash also accepted
+	assert.Equal(t, []string{"env"}, cfg.Tags)             // Env kept when no flag given
+	assert.Equal(t, "test-app", cfg.AppName)               // Default kept
+	assert.Equal(t, "internal-123", cfg.InternalID)        // Untagged fields have no flag
+	assert.Equal(t, "testuser", cfg.Database.Username)     // Default kept
+	assert.Equal(t, 5*time.Second, cfg.GracePeriod)        // Default kept
+	assert.Equal(t, []string{"127.0.0.1"}, cfg.AllowedIPs) // Default kept
+}
+
+func TestGenericBuilder_FlagsHelp(t *testing.T) {
+	var out bytes.Buffer
+	_, err := New(newTestConfig()).
+		EnvPrefix("TEST_").
+		Flags([]string{"--help"}).
+		FlagOutput(&out).
+		Build()
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, flag.ErrHelp))
+
+	help := out.String()
+	assert.Contains(t, help, "--app-name string")
+	assert.Contains(t, help, "sets AppName (env TEST_APP_NAME) (default \"test-app\")")
+	assert.Contains(t, help, "--db-port int")
+	assert.Contains(t, help, "sets Database.Port (env TEST_DB_PORT) (default \"5432\")")
+	assert.Contains(t, help, "--timeout time.Duration")
+	assert.Contains(t, help, "(default \"30s\")")
+	assert.NotContains(t, help, "internal")
+
+	// Secrets never show up as defaults, whatever layer set them
+	out.Reset()
+	_, err = New(newSecretConfig()).
+		EnvPrefix("HLP_").
+		Env(MapEnv{"HLP_DSN": "postgres://app:hunter2-super@db/app"}).
+		Flags([]string{"--help"}).
+		FlagOutput(&out).
+		Build()
+	require.True(t, errors.Is(err, flag.ErrHelp))
+
+	help = out.String()
+	assert.Contains(t, help, "--dsn string")
+	assert.Contains(t, help, "sets Name (env HLP_NAME) (default \"svc\")")
+	assert.NotContains(t, help, "hunter2-super")
+	assert.NotContains(t, help, "s3cr3t")
+	assert.NotContains(t, help, "1234")
+}
+
+func TestGenericBuilder_FlagsErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		errorMsg string
+	}{
+		{
+			name:     "invalid integer",
+			args:     []string{"--port", "not-an-int"},
+			errorMsg: "invalid integer value for --port",
+		},
+		{
+			name:     "invalid duration",
+			args:     []string{"--timeout=forever"},
+			errorMsg: "invalid duration value for --timeout",
+		},
+		{
+			name:     "unknown flag",
+			args:     []string{"--no-such-flag"},
+			errorMsg: "flag provided but not defined",
+		},
+		{
+			name:     "flag value fails validation",
+			args:     []string{"--port", "70000"},
+			errorMsg: "invalid configuration",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New(newTestConfig()).Flags(tt.args).FlagOutput(&bytes.Buffer{}).Build()
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.errorMsg)
+		})
+	}
+}
+
+func TestGenericBuilder_FlagsDuplicates(t *testing.T) {
+	type caseConfig struct {
+		Host      string `env:"db_host"`
+		OtherHost string `env:"DB_HOST"`
+	}
+	type autoConfig struct {
+		DBHost string
+		Host   string `env:"DB_HOST"`
+	}
+
+	_, err := New(&caseConfig{}).Env(MapEnv{}).Flags(nil).Build()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid flag --db-host: bound to both Host and OtherHost")
+
+	_, err = New(&autoConfig{}).Env(MapEnv{}).AutoEnv(SnakeUpper).Flags(nil).Build()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid flag --db-host: bound to both DBHost and Host")
+
+	// Without flags the duplicate names are harmless
+	_, err = New(&caseConfig{}).Env(MapEnv{}).Build()
+	require.NoError(t, err)
+}
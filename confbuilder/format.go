@@ -0,0 +1,214 @@
+package confbuilder
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// Format identifies the encoding of a configuration file
+type Format string
+
+const (
+	// FormatAuto detects the format from the file extension, falling back to JSON
+	FormatAuto Format = ""
+	// FormatJSON decodes the file as JSON
+	FormatJSON Format = "json"
+	// FormatYAML decodes the file as YAML
+	FormatYAML Format = "yaml"
+	// FormatTOML decodes the file as TOML
+	FormatTOML Format = "toml"
+)
+
+// SyntaxError reports a malformed configuration file with the position of the problem
+type SyntaxError struct {
+	File   string
+	Line   int // Line number starting at 1, 0 when unknown
+	Column int // Column number starting at 1, 0 when unknown
+	Msg    string
+}
+
+// Error implements the error interface
+func (e *SyntaxError) Error() string {
+	switch {
+	case e.Line > 0 && e.Column > 0:
+		return fmt.Sprintf("%s:%d:%d: %s", e.File, e.Line, e.Column, e.Msg)
+	case e.Line > 0:
+		return fmt.Sprintf("%s:%d: %s", e.File, e.Line, e.Msg)
+	default:
+		return fmt.Sprintf("%s: %s", e.File, e.Msg)
+	}
+}
+
+// formatFromPath returns the format matching the file extension, defaulting to JSON
+func formatFromPath(path string) Format {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		return FormatYAML
+	case ".toml":
+		return FormatTOML
+	default:
+		return FormatJSON
+	}
+}
+
+// parseTree decodes file contents into a generic tree of maps, slices and scalars
+func parseTree(path string, data []byte, format Format) (any, error) {
+	if format == FormatAuto {
+		format = formatFromPath(path)
+	}
+
+	switch format {
+	case FormatJSON:
+		return parseJSON(path, data)
+	case FormatYAML:
+		return parseYAML(path, data)
+	case FormatTOML:
+		return parseTOML(path, data)
+	default:
+		return nil, fmt.Errorf("unsupported config format %q", format)
+	}
+}
+
+// parseJSON decodes JSON keeping numbers intact to avoid float precision loss
+func parseJSON(path string, data []byte) (any, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	var tree any
+	if err := dec.Decode(&tree); err != nil {
+		var syntaxErr *json.SyntaxError
+		if errors.As(err, &syntaxErr) {
+			line, col := lineColumn(data, syntaxErr.Offset)
+			return nil, &SyntaxError{File: path, Line: line, Column: col, Msg: syntaxErr.Error()}
+		}
+		return nil, &SyntaxError{File: path, Msg: err.Error()}
+	}
+	return tree, nil
+}
+
+// parseYAML decodes YAML, normalising mapping keys to strings
+func parseYAML(path string, data []byte) (any, error) {
+	var node yaml.Node
+	if err := yaml.Unmarshal(data, &node); err != nil {
+		// yaml.v3 only exposes the line number inside the message
+		var line int
+		fmt.Sscanf(strings.TrimPrefix(err.Error(), "yaml: "), "line %d:", &line)
+		return nil, &SyntaxError{File: path, Line: line, Msg: err.Error()}
+	}
+	if node.Kind == 0 {
+		return nil, nil // Empty document
+	}
+
+	var tree any
+	if err := node.Decode(&tree); err != nil {
+		return nil, &SyntaxError{File: path, Line: node.Line, Column: node.Column, Msg: err.Error()}
+	}
+	return normalizeTree(tree), nil
+}
+
+// parseTOML decodes TOML documents
+func parseTOML(path string, data []byte) (any, error) {
+	var tree map[string]any
+	if err := toml.Unmarshal(data, &tree); err != nil {
+		var parseErr toml.ParseError
+		if errors.As(err, &parseErr) {
+			return nil, &SyntaxError{File: path, Line: parseErr.Position.Line, Column: parseErr.Position.Col, Msg: parseErr.Message}
+		}
+		return nil, &SyntaxError{File: path, Msg: err.Error()}
+	}
+	return tree, nil
+}
+
+// normalizeTree converts map[any]any values produced by YAML into map[string]any
+func normalizeTree(v any) any {
+	switch t := v.(type) {
+	case map[string]any:
+		for k, val := range t {
+			t[k] = normalizeTree(val)
+		}
+		return t
+	case map[any]any:
+		m := make(map[string]any, len(t))
+		for k, val := range t {
+			m[fmt.Sprint(k)] = normalizeTree(val)
+		}
+		return m
+	case []any:
+		for i, val := range t {
+			t[i] = normalizeTree(val)
+		}
+		return t
+	default:
+		return v
+	}
+}
+
+// copyTree deep copies the maps and slices of a generic tree, scalars are shared
+func copyTree(v any) any {
+	switch t := v.(type) {
+	case map[string]any:
+		m := make(map[string]any, len(t))
+		for k, val := range t {
+			m[k] = copyTree(val)
+		}
+		return m
+	case []any:
+		s := make([]any, len(t))
+		for i, val := range t {
+			s[i] = copyTree(val)
+		}
+		return s
+	default:
+		return v
+	}
+}
+
+// applyTree decodes a generic tree into target using the json struct tags
+func applyTree(target any, tree any) error {
+	if tree == nil {
+		return nil
+	}
+
+	data, err := json.Marshal(tree)
+	if err != nil {
+		return err
+	}
+
+	if err := json.Unmarshal(data, target); err != nil {
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &typeErr) && typeErr.Field != "" {
+			return fmt.Errorf("field %s: cannot use %s as %s", typeErr.Field, typeErr.Value, typeErr.Type)
+		}
+		return err
+	}
+	return nil
+}
+
+// lineColumn converts the offset reported by encoding/json, which points just past the
+// offending byte, into a 1-based line and column
+func lineColumn(data []byte, offset int64) (int, int) {
+	offset--
+	if offset > int64(len(data)) {
+		offset = int64(len(data))
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	line, col := 1, 1
+	for _, c := range data[:offset] {
+		if c == '\n' {
+			line++
+			col = 1
+		} else {
+			col++
+		}
+	}
+	return line, col
+}
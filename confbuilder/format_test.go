@@ -0,0 +1,184 @@
+package confbuilder
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenericBuilder_FileFormats(t *testing.T) {
+	tests := []struct {
+		name        string
+		fileName    string
+		format      Format
+		fileContent string
+	}{
+		{
+			name:     "yaml by extension",
+			fileName: "config.yaml",
+			fileContent: `
+app_name: yaml-app
+port: 9091
+tags: [yaml, config]
+database:
+  host: yaml-db
+  port: 3307
+`,
+		},
+		{
+			name:     "yml by extension",
+			fileName: "config.yml",
+			fileContent: `
+app_name: yaml-app
+port: 9091
+tags:
+  - yaml
+  - config
+database:
+  host: yaml-db
+  port: 3307
+`,
+		},
+		{
+			name:     "toml by extension",
+			fileName: "config.toml",
+			fileContent: `
+app_name = "yaml-app"
+port = 9091
+tags = ["yaml", "config"]
+
+[database]
+host = "yaml-db"
+port = 3307
+`,
+		},
+		{
+			name:     "explicit format overrides extension",
+			fileName: "config.conf",
+			format:   FormatYAML,
+			fileContent: `
+app_name: yaml-app
+port: 9091
+tags: [yaml, config]
+database: {host: yaml-db, port: 3307}
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			configPath := filepath.Join(t.TempDir(), tt.fileName)
+			err := os.WriteFile(configPath, []byte(tt.fileContent), 0644)
+			require.NoError(t, err)
+
+			cfg, err := New(newTestConfig()).File(&configPath).FileFormat(tt.format).Build()
+			require.NoError(t, err)
+
+			assert.Equal(t, "yaml-app", cfg.AppName)
+			assert.Equal(t, 9091, cfg.Port)
+			assert.Equal(t, []string{"yaml", "config"}, cfg.Tags)
+			assert.Equal(t, "yaml-db", cfg.Database.Host)
+			assert.Equal(t, 3307, cfg.Database.Port)
+			assert.Equal(t, "testuser", cfg.Database.Username) // From default
+		})
+	}
+}
+
+func TestGenericBuilder_FileFormatPrecedence(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	err := os.WriteFile(configPath, []byte("app_name: yaml-app\nport: 9091\n"), 0644)
+	require.NoError(t, err)
+
+	setEnvVars(t, map[string]string{"TEST_PORT": "6060"})
+
+	cfg, err := New(newTestConfig()).EnvPrefix("TEST_").File(&configPath).Build()
+	require.NoError(t, err)
+
+	assert.Equal(t, "yaml-app", cfg.AppName)        // From file
+	assert.Equal(t, 6060, cfg.Port)                 // From env (overrides file)
+	assert.Equal(t, "development", cfg.Environment) // From default
+}
+
+func TestGenericBuilder_FileFormatErrors(t *testing.T) {
+	tests := []struct {
+		name        string
+		fileName    string
+		fileContent string
+		line        int
+		column      int
+	}{
+		{
+			name:        "json syntax error",
+			fileName:    "config.json",
+			fileContent: "{\n  \"port\": 80,\n  \"tags\": [,]\n}",
+			line:        3,
+			column:      12,
+		},
+		{
+			name:        "yaml syntax error",
+			fileName:    "config.yaml",
+			fileContent: "app_name: ok\n  port: 1\n",
+			line:        2,
+		},
+		{
+			name:        "toml syntax error",
+			fileName:    "config.toml",
+			fileContent: "app_name = \"ok\"\nport = = 1\n",
+			line:        2,
+			column:      8,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			configPath := filepath.Join(t.TempDir(), tt.fileName)
+			err := os.WriteFile(configPath, []byte(tt.fileContent), 0644)
+			require.NoError(t, err)
+
+			_, err = New(newTestConfig()).File(&configPath).Build()
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), "failed to parse config file")
+
+			var syntaxErr *SyntaxError
+			require.True(t, errors.As(err, &syntaxErr))
+			assert.Equal(t, configPath, syntaxErr.File)
+			assert.Equal(t, tt.line, syntaxErr.Line)
+			assert.Equal(t, tt.column, syntaxErr.Column)
+		})
+	}
+
+	t.Run("type mismatch reports field", func(t *testing.T) {
+		configPath := filepath.Join(t.TempDir(), "config.yaml")
+		err := os.WriteFile(configPath, []byte("database:\n  port: not-a-port\n"), 0644)
+		require.NoError(t, err)
+
+		_, err = New(newTestConfig()).File(&configPath).Build()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "field database.port")
+		assert.Contains(t, err.Error(), "failed to parse config file: "+configPath+": field database.port")
+	})
+
+	t.Run("undecodable value reports file", func(t *testing.T) {
+		configPath := filepath.Join(t.TempDir(), "config.yaml")
+		err := os.WriteFile(configPath, []byte("timeout: forever\n"), 0644)
+		require.NoError(t, err)
+
+		_, err = New(newTestConfig()).File(&configPath).Build()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to parse config file: "+configPath+": invalid duration value for timeout")
+	})
+
+	t.Run("unsupported explicit format", func(t *testing.T) {
+		configPath := filepath.Join(t.TempDir(), "config.json")
+		err := os.WriteFile(configPath, []byte("{}"), 0644)
+		require.NoError(t, err)
+
+		_, err = New(newTestConfig()).File(&configPath).FileFormat("ini").Build()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), `unsupported config format "ini"`)
+	})
+}
@@ -0,0 +1,224 @@
+package confbuilder
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// isStructCollection reports whether t is a slice or a map of structs, filled from indexed env
+// variables such as UPSTREAMS_0_URL or TENANTS_ACME_QUOTA
+func isStructCollection(t reflect.Type) bool {
+	return (t.Kind() == reflect.Slice || t.Kind() == reflect.Map) && isStructType(t.Elem())
+}
+
+// loadIndexed fills the elements of a slice or map of structs from the env variables named
+// after the collection, the element index or key and the element field. Elements already
+// loaded from config files are merged, new ones start from their default tags
+func (l *envLoader) loadIndexed(f fieldInfo) error {
+	keys, err := elementKeys(f.value.Type().Elem(), l.naming)
+	if err != nil {
+		return err
+	}
+
+	base := l.prefix + f.envKey + "_"
+	segments := l.indexedSegments(base, keys)
+	if len(segments) == 0 {
+		return nil
+	}
+
+	if f.value.Kind() == reflect.Slice {
+		return l.loadSlice(f, base, segments)
+	}
+	return l.loadMap(f, base, segments)
+}
+
+// maxIndexedElements bounds the length slices can grow to from indexed env variables
+const maxIndexedElements = 1024
+
+// loadSlice fills the slice elements whose index appears in segments, growing the slice as needed
+func (l *envLoader) loadSlice(f fieldInfo, base string, segments []string) error {
+	var indexes []int
+	for _, segment := range segments {
+		i, err := strconv.Atoi(segment)
+		if err != nil || i < 0 {
+			continue
+		}
+
+		// Indexes come from variable names, a large one must not allocate a huge slice
+		if i >= f.value.Len() && i >= maxIndexedElements {
+			name := base + segment
+			l.fail(f, l.origin(name), segment, fmt.Errorf("invalid index %d for %s: at most %d elements can be set from env variables", i, name, maxIndexedElements))
+			continue
+		}
+		indexes = append(indexes, i)
+	}
+	if len(indexes) == 0 {
+		return nil
+	}
+	sort.Ints(indexes)
+
+	slice := f.value
+	if last := indexes[len(indexes)-1]; last >= slice.Len() {
+		slice = reflect.MakeSlice(f.value.Type(), last+1, last+1)
+		reflect.Copy(slice, f.value)
+		for i := f.value.Len(); i <= last; i++ {
+			if err := l.newElement(slice.Index(i)); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, i := range indexes {
+		elem := slice.Index(i)
+		if elem.Kind() == reflect.Ptr && elem.IsNil() {
+			if err := l.newElement(elem); err != nil {
+				return err
+			}
+		}
+		prefix := fmt.Sprintf("%s%d_", base, i)
+		path := fmt.Sprintf("%s[%d]", f.path, i)
+		if err := l.loadElement(elem, prefix, path); err != nil {
+			return err
+		}
+	}
+
+	f.value.Set(slice)
+	f.ensure()
+	return nil
+}
+
+// loadMap fills the map entries named in segments. Keys match existing entries case-insensitively,
+// new entries use the lowercased key, e.g. TENANTS_ACME_QUOTA sets Tenants["acme"].Quota
+func (l *envLoader) loadMap(f fieldInfo, base string, segments []string) error {
+	t := f.value.Type()
+	m := f.value
+	if m.IsNil() {
+		m = reflect.MakeMap(t)
+	}
+
+	for _, segment := range segments {
+		key, ok := existingKey(m, segment)
+		if !ok {
+			key = reflect.New(t.Key()).Elem()
+			name := base + segment
+			if err := l.decoders.setFieldValue(key, name, strings.ToLower(segment)); err != nil {
+				l.fail(f, l.origin(name), segment, err)
+				continue
+			}
+		}
+
+		// Map values are not addressable, so the element is loaded into a copy stored back afterwards
+		elem := reflect.New(t.Elem()).Elem()
+		existing := m.MapIndex(key)
+		if existing.IsValid() {
+			elem.Set(existing)
+		}
+		if !existing.IsValid() || (elem.Kind() == reflect.Ptr && elem.IsNil()) {
+			if err := l.newElement(elem); err != nil {
+				return err
+			}
+		}
+
+		path := fmt.Sprintf("%s[%s]", f.path, formatValue(key))
+		if err := l.loadElement(elem, base+segment+"_", path); err != nil {
+			return err
+		}
+		m.SetMapIndex(key, elem)
+	}
+
+	f.value.Set(m)
+	f.ensure()
+	return nil
+}
+
+// newElement initialises a new collection element from its default tags, allocating pointers
+func (l *envLoader) newElement(elem reflect.Value) error {
+	if elem.Kind() == reflect.Ptr {
+		elem.Set(reflect.New(elem.Type().Elem()))
+		return applyDefaults(elem.Interface(), l.naming, l.decoders)
+	}
+	return applyDefaults(elem.Addr().Interface(), l.naming, l.decoders)
+}
+
+// loadElement loads the env variables starting with prefix into a collection element
+func (l *envLoader) loadElement(elem reflect.Value, prefix, path string) error {
+	target := elem
+	if elem.Kind() != reflect.Ptr {
+		target = elem.Addr()
+	}
+
+	child := *l
+	child.prefix = prefix
+	child.pathPrefix = path
+	return child.loadEnvToStruct(target.Interface())
+}
+
+// indexedSegments returns, in order, the distinct indexes or map keys found in the names of the
+// env variables starting with base and followed by an element field, e.g. 0 for UPSTREAMS_0_URL
+func (l *envLoader) indexedSegments(base string, keys []string) []string {
+	seen := map[string]bool{}
+	var segments []string
+	for _, name := range l.env.Keys() {
+		rest, ok := strings.CutPrefix(name, base)
+		if !ok {
+			continue
+		}
+		if l.secretFiles {
+			rest = strings.TrimSuffix(rest, fileSuffix)
+		}
+
+		// Keys may contain underscores, the shortest segment followed by a field wins so that
+		// nested collections such as 0_HEADERS_1_NAME resolve to element 0
+		segment := ""
+		for _, key := range keys {
+			candidate, ok := strings.CutSuffix(rest, "_"+key)
+			if !ok {
+				i := strings.Index(rest, "_"+key+"_")
+				if i < 0 {
+					continue
+				}
+				candidate = rest[:i]
+			}
+			if candidate != "" && (segment == "" || len(candidate) < len(segment)) {
+				segment = candidate
+			}
+		}
+
+		if segment != "" && !seen[segment] {
+			seen[segment] = true
+			segments = append(segments, segment)
+		}
+	}
+	sort.Strings(segments)
+	return segments
+}
+
+// elementKeys returns the env keys of the fields of a collection element type
+func elementKeys(elemType reflect.Type, naming envNaming) ([]string, error) {
+	if elemType.Kind() == reflect.Ptr {
+		elemType = elemType.Elem()
+	}
+
+	var keys []string
+	err := walkFields(reflect.New(elemType).Interface(), naming, func(f fieldInfo) error {
+		if f.envKey != "" {
+			keys = append(keys, f.envKey)
+		}
+		return nil
+	})
+	return keys, err
+}
+
+// existingKey returns the key of m matching segment case-insensitively
+func existingKey(m reflect.Value, segment string) (reflect.Value, bool) {
+	iter := m.MapRange()
+	for iter.Next() {
+		if strings.EqualFold(formatValue(iter.Key()), segment) {
+			return iter.Key(), true
+		}
+	}
+	return reflect.Value{}, false
+}
@@ -0,0 +1,130 @@
+package confbuilder
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type IndexedConfig struct {
+	Upstreams []UpstreamConfig        `json:"upstreams" env:"UPSTREAMS" validate:"dive"`
+	Tenants   map[string]TenantConfig `json:"tenants" env:"TENANTS"`
+	Mirrors   []*UpstreamConfig       `json:"mirrors" env:"MIRRORS"`
+}
+
+type UpstreamConfig struct {
+	URL     string   `json:"url" env:"URL" validate:"required,url"`
+	Weight  int      `json:"weight" env:"WEIGHT" default:"1"`
+	Headers []Header `json:"headers" env:"HEADERS"`
+}
+
+type Header struct {
+	Name  string `json:"name" env:"NAME"`
+	Value string `json:"value" env:"VALUE"`
+}
+
+type TenantConfig struct {
+	Quota    int    `json:"quota" env:"QUOTA"`
+	Plan     string `json:"plan" env:"PLAN" default:"free"`
+	Password string `json:"password" env:"PASSWORD" secret:"true"`
+}
+
+func TestGenericBuilder_IndexedEnv(t *testing.T) {
+	setEnvVars(t, map[string]string{
+		"IDX_UPSTREAMS_0_WEIGHT":         "5",
+		"IDX_UPSTREAMS_1_URL":            "http://b.internal",
+		"IDX_UPSTREAMS_1_HEADERS_0_NAME": "X-Env",
+		"IDX_TENANTS_ACME_QUOTA":         "100",
+		"IDX_TENANTS_GLOBEX_EU_QUOTA":    "7",
+		"IDX_MIRRORS_0_URL":              "http://mirror.internal",
+	})
+
+	configPath := writeFile(t, t.TempDir(), "config.json", `{
+		"upstreams": [{"url": "http://a.internal", "weight": 2}],
+		"tenants": {"acme": {"plan": "gold"}}
+	}`)
+
+	cfg, report, err := New(&IndexedConfig{}).EnvPrefix("IDX_").File(&configPath).BuildWithReport()
+	require.NoError(t, err)
+
+	// File elements are merged with indexed variables, new elements start from default tags
+	assert.Equal(t, []UpstreamConfig{
+		{URL: "http://a.internal", Weight: 5},
+		{URL: "http://b.internal", Weight: 1, Headers: []Header{{Name: "X-Env"}}},
+	}, cfg.Upstreams)
+
+	assert.Equal(t, map[string]TenantConfig{
+		"acme":      {Quota: 100, Plan: "gold"},
+		"globex_eu": {Quota: 7, Plan: "free"},
+	}, cfg.Tenants)
+
+	require.Len(t, cfg.Mirrors, 1)
+	assert.Equal(t, "http://mirror.internal", cfg.Mirrors[0].URL)
+
+	assert.Equal(t, Origin{Source: SourceEnv, Key: "IDX_UPSTREAMS_1_URL"}, report.Provenance["Upstreams[1].URL"])
+	assert.Equal(t, Origin{Source: SourceEnv, Key: "IDX_UPSTREAMS_1_HEADERS_0_NAME"}, report.Provenance["Upstreams[1].Headers[0].Name"])
+	assert.Equal(t, Origin{Source: SourceEnv, Key: "IDX_TENANTS_ACME_QUOTA"}, report.Provenance["Tenants[acme].Quota"])
+}
+
+func TestGenericBuilder_IndexedEnvErrors(t *testing.T) {
+	setEnvVars(t, map[string]string{
+		"IDX_UPSTREAMS_0_URL":      "http://a.internal",
+		"IDX_UPSTREAMS_1_URL":      "not a url",
+		"IDX_UPSTREAMS_1_WEIGHT":   "heavy",
+		"IDX_TENANTS_ACME_QUOTA":   "lots",
+		"IDX_TENANTS_ACME_UNKNOWN": "ignored",
+	})
+
+	_, err := New(&IndexedConfig{}).EnvPrefix("IDX_").Build()
+	require.Error(t, err)
+
+	var cfgErr *ConfigError
+	require.True(t, errors.As(err, &cfgErr))
+
+	byPath := map[string]FieldError{}
+	for _, fe := range cfgErr.Fields {
+		byPath[fe.Path] = fe
+	}
+
+	weight := byPath["Upstreams[1].Weight"]
+	assert.Equal(t, "IDX_UPSTREAMS_1_WEIGHT", weight.EnvVar)
+	assert.Contains(t, weight.Message, "invalid integer value for IDX_UPSTREAMS_1_WEIGHT")
+
+	assert.Contains(t, byPath["Tenants[acme].Quota"].Message, "invalid integer value for IDX_TENANTS_ACME_QUOTA")
+
+	url := byPath["Upstreams[1].URL"]
+	assert.Equal(t, "IDX_UPSTREAMS_1_URL must be a valid URL", url.Message)
+	assert.Equal(t, SourceEnv, url.Source)
+}
+
+func TestGenericBuilder_IndexedEnvLargeIndex(t *testing.T) {
+	env := MapEnv{
+		"IDX_UPSTREAMS_0_URL":                   "http://a.internal",
+		"IDX_UPSTREAMS_9223372036854775806_URL": "http://b.internal",
+		"IDX_MIRRORS_1024_URL":                  "http://c.internal",
+	}
+
+	_, err := New(&IndexedConfig{}).EnvPrefix("IDX_").Env(env).Build()
+	require.Error(t, err)
+
+	var cfgErr *ConfigError
+	require.True(t, errors.As(err, &cfgErr))
+	require.Len(t, cfgErr.Fields, 2)
+	assert.Equal(t, "Upstreams", cfgErr.Fields[0].Path)
+	assert.Contains(t, cfgErr.Fields[0].Message, "at most 1024 elements can be set from env variables")
+	assert.Equal(t, "Mirrors", cfgErr.Fields[1].Path)
+	assert.Equal(t, "IDX_MIRRORS_1024", cfgErr.Fields[1].EnvVar)
+	assert.Contains(t, cfgErr.Fields[1].Message, "invalid index 1024 for IDX_MIRRORS_1024")
+}
+
+func TestGenericBuilder_IndexedFlags(t *testing.T) {
+	var out bytes.Buffer
+	_, err := New(&IndexedConfig{}).EnvPrefix("IDX_").Env(MapEnv{}).Flags([]string{"--help"}).FlagOutput(&out).Build()
+	require.True(t, errors.Is(err, flag.ErrHelp))
+	assert.NotContains(t, out.String(), "--upstreams")
+	assert.NotContains(t, out.String(), "--tenants")
+}
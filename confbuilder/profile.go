@@ -0,0 +1,103 @@
+package confbuilder
+
+import (
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"strings"
+)
+
+// profileTag marks the string field receiving the active profile, e.g. `profile:"true"`
+const profileTag = "profile"
+
+// Profile selects the configuration profile, e.g. dev, staging or prod. Every configuration
+// file gets an optional config.<profile>.ext layer merged after it and every .env file an
+// .env.<profile> variant winning over it
+func (b *Builder[T]) Profile(name string) *Builder[T] {
+	b.profile = name
+	return b
+}
+
+// ProfileEnv reads the profile from the env variable name, e.g. APP_PROFILE, when no profile
+// is selected with Profile. The variable is looked up in the env source, not in .env files
+func (b *Builder[T]) ProfileEnv(name string) *Builder[T] {
+	b.profileEnv = name
+	return b
+}
+
+// reservedEnv returns the env variables read by the builder rather than bound to fields,
+// accepted by strict mode even when they share the prefix
+func (b *Builder[T]) reservedEnv() []string {
+	if b.profileEnv == "" {
+		return nil
+	}
+	return []string{b.profileEnv, b.profileEnv + fileSuffix}
+}
+
+// activeProfile returns the selected profile, empty when there is none
+func (b *Builder[T]) activeProfile() (string, error) {
+	profile := b.profile
+	if profile == "" && b.profileEnv != "" {
+		profile, _ = b.env.Lookup(b.profileEnv)
+	}
+
+	// Profiles become part of file names
+	if profile != "" && (strings.ContainsAny(profile, `/\`) || !filepath.IsLocal(profile)) {
+		return "", fmt.Errorf("invalid profile %q", profile)
+	}
+	return profile, nil
+}
+
+// profileLayers adds the optional profile variant of every layer right after it
+func profileLayers(layers []FileLayer, profile string) []FileLayer {
+	if profile == "" {
+		return layers
+	}
+
+	result := make([]FileLayer, 0, 2*len(layers))
+	for _, layer := range layers {
+		variant := layer
+		variant.Path = profilePath(layer.Path, profile)
+		variant.Optional = true
+		result = append(result, layer, variant)
+	}
+	return result
+}
+
+// profilePath inserts the profile before the extension, e.g. config.json becomes config.prod.json
+func profilePath(path, profile string) string {
+	ext := filepath.Ext(path)
+	return strings.TrimSuffix(path, ext) + "." + profile + ext
+}
+
+// profileEnvFiles puts the profile variant of every .env file name before it so that it wins,
+// e.g. .env becomes .env.prod and .env
+func profileEnvFiles(names []string, profile string) []string {
+	if profile == "" {
+		return names
+	}
+
+	result := make([]string, 0, 2*len(names))
+	for _, name := range names {
+		result = append(result, name+"."+profile, name)
+	}
+	return result
+}
+
+// setProfile stores the profile in the string fields of target tagged profile:"true"
+func setProfile(target any, naming envNaming, profile string) error {
+	if profile == "" {
+		return nil
+	}
+	return walkFields(target, naming, func(f fieldInfo) error {
+		if f.field.Tag.Get(profileTag) != "true" {
+			return nil
+		}
+		if f.value.Kind() != reflect.String {
+			return fmt.Errorf("profile field %s must be a string", f.path)
+		}
+		f.value.SetString(profile)
+		f.ensure()
+		return nil
+	})
+}
@@ -0,0 +1,90 @@
+package confbuilder
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type ProfileConfig struct {
+	Profile string `json:"-" profile:"true"`
+	Port    int    `json:"port" env:"PORT"`
+	Name    string `json:"name" env:"NAME"`
+	APIKey  string `json:"api_key" env:"API_KEY" validate:"required_if=Profile prod"`
+}
+
+func TestGenericBuilder_Profile(t *testing.T) {
+	dir := t.TempDir()
+	configPath := writeFile(t, dir, "config.json", `{"port": 1000, "name": "file"}`)
+	prodConfigPath := writeFile(t, dir, "config.prod.json", `{"port": 2000}`)
+	writeFile(t, dir, ".env.profile", "PRF_NAME=base\n")
+	prodEnvPath := writeFile(t, dir, ".env.profile.prod", "PRF_NAME=prod\n")
+
+	originalWD, err := os.Getwd()
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(dir))
+	t.Cleanup(func() { os.Chdir(originalWD) })
+
+	newBuilder := func(env MapEnv) *Builder[*ProfileConfig] {
+		return New(&ProfileConfig{Profile: "none"}).
+			EnvPrefix("PRF_").
+			Env(env).
+			File(&configPath).
+			EnvFiles(".env.profile")
+	}
+
+	t.Run("Explicit profile", func(t *testing.T) {
+		cfg, report, err := newBuilder(MapEnv{"PRF_API_KEY": "key"}).Profile("prod").BuildWithReport()
+		require.NoError(t, err)
+		assert.Equal(t, "prod", cfg.Profile)
+		assert.Equal(t, 2000, cfg.Port)
+		assert.Equal(t, "prod", cfg.Name)
+		assert.Equal(t, "prod", report.Profile)
+		assert.Equal(t, Origin{Source: SourceFile, File: prodConfigPath}, report.Provenance["Port"])
+		assert.Equal(t, Origin{Source: SourceEnvFile, Key: "PRF_NAME", File: prodEnvPath}, report.Provenance["Name"])
+	})
+
+	t.Run("Validation reads the profile", func(t *testing.T) {
+		_, err := newBuilder(MapEnv{}).Profile("prod").Build()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "PRF_API_KEY is required when Profile is prod")
+	})
+
+	t.Run("Profile from env variable", func(t *testing.T) {
+		cfg, err := newBuilder(MapEnv{"PRF_PROFILE": "staging"}).ProfileEnv("PRF_PROFILE").Build()
+		require.NoError(t, err)
+		assert.Equal(t, "staging", cfg.Profile)
+		assert.Equal(t, 1000, cfg.Port) // Missing profile files are optional
+		assert.Equal(t, "base", cfg.Name)
+	})
+
+	t.Run("Explicit profile wins over env variable", func(t *testing.T) {
+		cfg, err := newBuilder(MapEnv{"PRF_PROFILE": "prod"}).ProfileEnv("PRF_PROFILE").Profile("staging").Build()
+		require.NoError(t, err)
+		assert.Equal(t, "staging", cfg.Profile)
+	})
+
+	t.Run("No profile", func(t *testing.T) {
+		cfg, report, err := newBuilder(MapEnv{}).BuildWithReport()
+		require.NoError(t, err)
+		assert.Equal(t, "none", cfg.Profile)
+		assert.Equal(t, 1000, cfg.Port)
+		assert.Equal(t, "base", cfg.Name)
+		assert.Equal(t, "", report.Profile)
+	})
+
+	t.Run("Invalid profile", func(t *testing.T) {
+		_, err := newBuilder(MapEnv{}).Profile("../prod").Build()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), `invalid profile "../prod"`)
+	})
+}
+
+func TestProfilePaths(t *testing.T) {
+	assert.Equal(t, "conf/config.prod.yaml", profilePath("conf/config.yaml", "prod"))
+	assert.Equal(t, "config.dev", profilePath("config", "dev"))
+	assert.Equal(t, []string{".env.dev", ".env", ".env.local.dev", ".env.local"}, profileEnvFiles([]string{".env", ".env.local"}, "dev"))
+	assert.Equal(t, []string{".env"}, profileEnvFiles([]string{".env"}, ""))
+}
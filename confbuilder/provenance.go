@@ -0,0 +1,130 @@
+package confbuilder
+
+import (
+	"log/slog"
+	"sort"
+	"strings"
+)
+
+// SourceKind identifies the layer a configuration value was loaded from
+type SourceKind string
+
+const (
+	// SourceDefault marks values kept from the default configuration
+	SourceDefault SourceKind = "default"
+	// SourceFile marks values read from a configuration file
+	SourceFile SourceKind = "file"
+	// SourceRemote marks values fetched from a remote configuration endpoint
+	SourceRemote SourceKind = "remote"
+	// SourceEnvFile marks values read from a .env file
+	SourceEnvFile SourceKind = "envfile"
+	// SourceEnv marks values read from the process environment
+	SourceEnv SourceKind = "env"
+	// SourceFlag marks values read from command-line flags
+	SourceFlag SourceKind = "flag"
+)
+
+// Origin records where a configuration value was loaded from
+type Origin struct {
+	Source SourceKind
+	Key    string // Env variable or flag name that set the value
+	File   string // Configuration or .env file path, or remote URL, that set the value
+}
+
+// String renders the origin for logs, e.g. "envfile TEST_DB_PORT (/app/.env)"
+func (o Origin) String() string {
+	parts := []string{string(o.Source)}
+	if o.Key != "" {
+		parts = append(parts, o.Key)
+	}
+	if o.File != "" {
+		if o.Key != "" {
+			parts = append(parts, "("+o.File+")")
+		} else {
+			parts = append(parts, o.File)
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// Provenance maps field paths such as Database.Port to the origin of their final value
+type Provenance map[string]Origin
+
+// Paths returns the recorded field paths in sorted order
+func (p Provenance) Paths() []string {
+	paths := make([]string, 0, len(p))
+	for path := range p {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// Report describes how a configuration was built
+type Report struct {
+	Provenance Provenance
+	EnvFiles   []string // .env files that were loaded, the winning one first
+	Profile    string   // Active profile, empty when none is selected
+
+	watchFiles []string // Files whose changes make watchers reload, including missing optional ones
+}
+
+// newReport returns an empty build report
+func newReport() *Report {
+	return &Report{Provenance: Provenance{}}
+}
+
+// LogProvenance logs the origin of every configuration value, typically once at startup
+func LogProvenance(logger *slog.Logger, p Provenance) {
+	for _, path := range p.Paths() {
+		origin := p[path]
+		attrs := []any{"field", path, "source", string(origin.Source)}
+		if origin.Key != "" {
+			attrs = append(attrs, "key", origin.Key)
+		}
+		if origin.File != "" {
+			attrs = append(attrs, "file", origin.File)
+		}
+		logger.Info("Configuration value", attrs...)
+	}
+}
+
+// recordDefaults marks every leaf field of target as coming from the defaults
+func recordDefaults(target any, naming envNaming, p Provenance) error {
+	return walkFields(target, naming, func(f fieldInfo) error {
+		p[f.path] = Origin{Source: SourceDefault}
+		return nil
+	})
+}
+
+// recordTree marks the leaf fields of target set by a configuration file tree
+func recordTree(target any, naming envNaming, file loadedFile, p Provenance) error {
+	return walkFields(target, naming, func(f fieldInfo) error {
+		if f.jsonPath != nil && treeHas(file.tree, f.jsonPath) {
+			p[f.path] = Origin{Source: file.source, File: file.path}
+		}
+		return nil
+	})
+}
+
+// treeHas reports whether the tree contains a value at the key path, matching keys
+// case-insensitively like encoding/json
+func treeHas(tree any, keys []string) bool {
+	for _, key := range keys {
+		m, ok := tree.(map[string]any)
+		if !ok {
+			return false
+		}
+		found := false
+		for k, v := range m {
+			if strings.EqualFold(k, key) {
+				tree, found = v, true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return tree != nil
+}
@@ -0,0 +1,97 @@
+package confbuilder
+
+import (
+	"bytes"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenericBuilder_Provenance(t *testing.T) {
+	tempDir := t.TempDir()
+	configPath := writeFile(t, tempDir, "config.json", `{"app_name": "file-app", "database": {"host": "file-db"}}`)
+	envPath := writeFile(t, tempDir, ".env.provenance", "TEST_DB_USERNAME=envfileuser\n")
+
+	setEnvVars(t, map[string]string{"TEST_PORT": "6060"})
+	t.Cleanup(func() { os.Unsetenv("TEST_DB_USERNAME") })
+
+	originalWD, err := os.Getwd()
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(tempDir))
+	t.Cleanup(func() { os.Chdir(originalWD) })
+
+	cfg, report, err := New(newTestConfig()).
+		EnvPrefix("TEST_").
+		File(&configPath).
+		EnvFiles(".env.provenance").
+		Flags([]string{"--db-port", "6543"}).
+		BuildWithReport()
+	require.NoError(t, err)
+	require.NotNil(t, report)
+
+	assert.Equal(t, "envfileuser", cfg.Database.Username)
+
+	expected := map[string]Origin{
+		"AppName":           {Source: SourceFile, File: configPath},
+		"Database.Host":     {Source: SourceFile, File: configPath},
+		"Port":              {Source: SourceEnv, Key: "TEST_PORT"},
+		"Database.Username": {Source: SourceEnvFile, Key: "TEST_DB_USERNAME", File: envPath},
+		"Database.Port":     {Source: SourceFlag, Key: "--db-port"},
+		"Environment":       {Source: SourceDefault},
+		"InternalID":        {Source: SourceDefault},
+	}
+	for path, origin := range expected {
+		assert.Equal(t, origin, report.Provenance[path], path)
+	}
+	assert.NotContains(t, report.Provenance, "unexported")
+}
+
+func TestGenericBuilder_ProvenanceOnError(t *testing.T) {
+	defaultCfg := newTestConfig()
+	defaultCfg.AppName = ""
+
+	_, report, err := New(defaultCfg).BuildWithReport()
+	require.Error(t, err)
+	require.NotNil(t, report)
+	assert.Equal(t, Origin{Source: SourceDefault}, report.Provenance["AppName"])
+}
+
+func TestOrigin_String(t *testing.T) {
+	tests := []struct {
+		origin   Origin
+		expected string
+	}{
+		{Origin{Source: SourceDefault}, "default"},
+		{Origin{Source: SourceFile, File: "config.json"}, "file config.json"},
+		{Origin{Source: SourceEnv, Key: "TEST_PORT"}, "env TEST_PORT"},
+		{Origin{Source: SourceEnvFile, Key: "TEST_PORT", File: "/app/.env"}, "envfile TEST_PORT (/app/.env)"},
+		{Origin{Source: SourceFlag, Key: "--port"}, "flag --port"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expected, func(t *testing.T) {
+			assert.Equal(t, tt.expected, tt.origin.String())
+		})
+	}
+}
+
+func TestLogProvenance(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, nil))
+
+	LogProvenance(logger, Provenance{
+		"Port":          {Source: SourceEnv, Key: "TEST_PORT"},
+		"AppName":       {Source: SourceFile, File: filepath.Join("etc", "config.json")},
+		"Database.Host": {Source: SourceDefault},
+	})
+
+	lines := bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n"))
+	require.Len(t, lines, 3)
+	assert.Contains(t, string(lines[0]), "field=AppName source=file file=etc/config.json")
+	assert.Contains(t, string(lines[1]), "field=Database.Host source=default")
+	assert.Contains(t, string(lines[2]), "field=Port source=env key=TEST_PORT")
+}
@@ -0,0 +1,215 @@
+package confbuilder
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// defaultRemoteTimeout bounds remote fetches when Remote.Timeout is not set
+const defaultRemoteTimeout = 10 * time.Second
+
+// Remote describes a configuration document fetched over HTTP, e.g. from a config service
+type Remote struct {
+	URL       string
+	Format    Format        // Detected from the Content-Type header, then from the URL path, when empty
+	Timeout   time.Duration // Bound on each fetch, 10s when zero
+	Token     string        // Sent as a bearer token when set
+	CacheFile string        // Last good document kept on disk and used when a fetch fails, disabled when empty
+	Client    *http.Client  // http.DefaultClient when nil
+}
+
+// Remote adds a configuration document fetched from r.URL, merged after the configuration
+// files and before the env layers. Responses are cached by ETag across builds
+func (b *Builder[T]) Remote(r Remote) *Builder[T] {
+	b.remote = &remoteSource{config: r}
+	return b
+}
+
+// remoteSource fetches a remote document, remembering the last good one
+type remoteSource struct {
+	config Remote
+
+	mu     sync.Mutex // Serialises fetches, e.g. a build racing a watcher reload
+	cached *remoteDocument
+}
+
+// remoteDocument is a fetched document together with what is needed to revalidate it
+type remoteDocument struct {
+	ETag   string `json:"etag"`
+	Format Format `json:"format"`
+	Data   []byte `json:"-"`
+}
+
+// load fetches and parses the document, falling back to the last good copy when the fetch
+// fails or returns a document that does not parse
+func (s *remoteSource) load(ctx context.Context) (loadedFile, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.cached == nil {
+		s.cached = s.readCache()
+	}
+
+	doc, err := s.fetch(ctx)
+	var tree any
+	if err == nil {
+		tree, err = parseTree(s.config.URL, doc.Data, doc.Format)
+	}
+	if err != nil {
+		if s.cached == nil {
+			return loadedFile{}, fmt.Errorf("failed to fetch remote config %s: %w", s.config.URL, err)
+		}
+		slog.Warn("Using cached remote config", "url", s.config.URL, "error", err)
+		doc = s.cached
+		if tree, err = parseTree(s.config.URL, doc.Data, doc.Format); err != nil {
+			return loadedFile{}, fmt.Errorf("failed to parse cached remote config: %w", err)
+		}
+	}
+
+	if doc != s.cached {
+		s.cached = doc
+		if err := s.writeCache(doc); err != nil {
+			slog.Warn("Failed to cache remote config", "file", s.config.CacheFile, "error", err)
+		}
+	}
+	return loadedFile{path: s.config.URL, tree: tree, source: SourceRemote}, nil
+}
+
+// fetch requests the document, revalidating the cached copy with its ETag
+func (s *remoteSource) fetch(ctx context.Context) (*remoteDocument, error) {
+	timeout := s.config.Timeout
+	if timeout <= 0 {
+		timeout = defaultRemoteTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.config.URL, nil)
+	if err != nil {
+		return nil, err
+	}
+	if s.config.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+s.config.Token)
+	}
+	if s.cached != nil && s.cached.ETag != "" {
+		req.Header.Set("If-None-Match", s.cached.ETag)
+	}
+
+	client := s.config.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotModified && s.cached != nil:
+		return s.cached, nil
+	case resp.StatusCode != http.StatusOK:
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	return &remoteDocument{
+		ETag:   resp.Header.Get("ETag"),
+		Format: s.format(resp.Header.Get("Content-Type")),
+		Data:   data,
+	}, nil
+}
+
+// format returns the format of a response, the configured one winning over the media type
+func (s *remoteSource) format(contentType string) Format {
+	if s.config.Format != FormatAuto {
+		return s.config.Format
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch mediaType {
+	case "application/json":
+		return FormatJSON
+	case "application/yaml", "application/x-yaml", "text/yaml", "text/x-yaml":
+		return FormatYAML
+	case "application/toml", "text/toml":
+		return FormatTOML
+	}
+
+	if u, err := url.Parse(s.config.URL); err == nil {
+		return formatFromPath(u.Path)
+	}
+	return FormatJSON
+}
+
+// readCache returns the document cached on disk, nil when there is none
+func (s *remoteSource) readCache() *remoteDocument {
+	if s.config.CacheFile == "" {
+		return nil
+	}
+
+	data, err := os.ReadFile(s.config.CacheFile)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			slog.Warn("Failed to read remote config cache", "file", s.config.CacheFile, "error", err)
+		}
+		return nil
+	}
+
+	doc := &remoteDocument{Format: s.format(""), Data: data}
+	if meta, err := os.ReadFile(s.config.CacheFile + ".meta"); err == nil {
+		if err := json.Unmarshal(meta, doc); err != nil {
+			slog.Warn("Ignoring invalid remote config cache metadata", "file", s.config.CacheFile+".meta", "error", err)
+		}
+	}
+	return doc
+}
+
+// writeCache stores the document and its metadata next to each other, replacing the files
+// atomically so that a crash never leaves a truncated copy
+func (s *remoteSource) writeCache(doc *remoteDocument) error {
+	if s.config.CacheFile == "" {
+		return nil
+	}
+
+	meta, err := json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(s.config.CacheFile, doc.Data); err != nil {
+		return err
+	}
+	return writeFileAtomic(s.config.CacheFile+".meta", meta)
+}
+
+// writeFileAtomic writes data to a temporary file renamed over path
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // No-op once renamed
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
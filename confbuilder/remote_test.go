@@ -0,0 +1,182 @@
+package confbuilder
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// configServer serves body with an ETag, answering 304 to matching revalidations
+type configServer struct {
+	body        atomic.Value
+	contentType string
+	status      atomic.Int32
+	delay       time.Duration
+	fetches     atomic.Int32
+	notModified atomic.Int32
+	auth        atomic.Value
+}
+
+func newConfigServer(t *testing.T, contentType, body string) (*configServer, *httptest.Server) {
+	s := &configServer{contentType: contentType}
+	s.body.Store(body)
+	s.status.Store(http.StatusOK)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(s.delay)
+		s.auth.Store(r.Header.Get("Authorization"))
+		if status := int(s.status.Load()); status != http.StatusOK {
+			w.WriteHeader(status)
+			return
+		}
+
+		body := s.body.Load().(string)
+		etag := `"` + body + `"`
+		if r.Header.Get("If-None-Match") == etag {
+			s.notModified.Add(1)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		s.fetches.Add(1)
+		w.Header().Set("ETag", etag)
+		w.Header().Set("Content-Type", s.contentType)
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(srv.Close)
+	return s, srv
+}
+
+func TestGenericBuilder_Remote(t *testing.T) {
+	dir := t.TempDir()
+	configPath := writeFile(t, dir, "config.json", `{"app_name": "from-file", "port": 8081, "log_level": "WARN"}`)
+	server, srv := newConfigServer(t, "application/json; charset=utf-8", `{"port": 8082, "log_level": "DEBUG"}`)
+
+	b := New(newTestConfig()).
+		EnvPrefix("REMOTE_").
+		Env(MapEnv{"REMOTE_LOG_LEVEL": "ERROR"}).
+		File(&configPath).
+		Remote(Remote{URL: srv.URL + "/config", Token: "s3cret"})
+
+	cfg, report, err := b.BuildWithReport()
+	require.NoError(t, err)
+
+	// Remote values override the file and env variables override the remote ones
+	assert.Equal(t, "from-file", cfg.AppName)
+	assert.Equal(t, 8082, cfg.Port)
+	assert.Equal(t, "ERROR", cfg.LogLevel.String())
+	assert.Equal(t, Origin{Source: SourceRemote, File: srv.URL + "/config"}, report.Provenance["Port"])
+	assert.Equal(t, "Bearer s3cret", server.auth.Load())
+
+	// Rebuilds revalidate the document with its ETag
+	cfg, err = b.Build()
+	require.NoError(t, err)
+	assert.Equal(t, 8082, cfg.Port)
+	assert.Equal(t, int32(1), server.fetches.Load())
+	assert.Equal(t, int32(1), server.notModified.Load())
+
+	server.body.Store(`{"port": 8083}`)
+	cfg, err = b.Build()
+	require.NoError(t, err)
+	assert.Equal(t, 8083, cfg.Port)
+	assert.Equal(t, int32(2), server.fetches.Load())
+}
+
+func TestGenericBuilder_RemoteFormat(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		path        string
+		format      Format
+		body        string
+	}{
+		{name: "Content type", contentType: "application/yaml", path: "/config", body: "port: 9001\n"},
+		{name: "URL extension", contentType: "application/octet-stream", path: "/config.toml", body: "port = 9001\n"},
+		{name: "Configured", contentType: "text/plain", path: "/config", format: FormatYAML, body: "port: 9001\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, srv := newConfigServer(t, tt.contentType, tt.body)
+
+			cfg, err := New(newTestConfig()).Env(MapEnv{}).Remote(Remote{URL: srv.URL + tt.path, Format: tt.format}).Build()
+			require.NoError(t, err)
+			assert.Equal(t, 9001, cfg.Port)
+		})
+	}
+}
+
+func TestGenericBuilder_RemoteFallback(t *testing.T) {
+	t.Run("Disk cache", func(t *testing.T) {
+		cacheFile := filepath.Join(t.TempDir(), "remote.json")
+		server, srv := newConfigServer(t, "application/json", `{"port": 8082}`)
+		remote := Remote{URL: srv.URL, CacheFile: cacheFile}
+
+		_, err := New(newTestConfig()).Env(MapEnv{}).Remote(remote).Build()
+		require.NoError(t, err)
+		assert.FileExists(t, cacheFile)
+
+		// A new process revalidates the cached copy
+		cfg, err := New(newTestConfig()).Env(MapEnv{}).Remote(remote).Build()
+		require.NoError(t, err)
+		assert.Equal(t, 8082, cfg.Port)
+		assert.Equal(t, int32(1), server.notModified.Load())
+
+		// And falls back to it when the service is down
+		srv.Close()
+		cfg, report, err := New(newTestConfig()).Env(MapEnv{}).Remote(remote).BuildWithReport()
+		require.NoError(t, err)
+		assert.Equal(t, 8082, cfg.Port)
+		assert.Equal(t, SourceRemote, report.Provenance["Port"].Source)
+	})
+
+	t.Run("Failed fetches keep the last good document", func(t *testing.T) {
+		server, srv := newConfigServer(t, "application/json", `{"port": 8082}`)
+		b := New(newTestConfig()).Env(MapEnv{}).Remote(Remote{URL: srv.URL})
+		_, err := b.Build()
+		require.NoError(t, err)
+
+		server.status.Store(http.StatusInternalServerError)
+		cfg, err := b.Build()
+		require.NoError(t, err)
+		assert.Equal(t, 8082, cfg.Port)
+
+		server.status.Store(http.StatusOK)
+		server.body.Store(`{"port": `)
+		cfg, err = b.Build()
+		require.NoError(t, err)
+		assert.Equal(t, 8082, cfg.Port)
+	})
+
+	t.Run("No cache", func(t *testing.T) {
+		server, srv := newConfigServer(t, "application/json", `{"port": 8082}`)
+		server.status.Store(http.StatusUnauthorized)
+
+		_, err := New(newTestConfig()).Env(MapEnv{}).Remote(Remote{URL: srv.URL}).Build()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to fetch remote config")
+		assert.Contains(t, err.Error(), "401 Unauthorized")
+	})
+
+	t.Run("Timeout", func(t *testing.T) {
+		server, srv := newConfigServer(t, "application/json", `{"port": 8082}`)
+		server.delay = 200 * time.Millisecond
+
+		_, err := New(newTestConfig()).Env(MapEnv{}).Remote(Remote{URL: srv.URL, Timeout: 20 * time.Millisecond}).Build()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "context deadline exceeded")
+	})
+}
+
+func TestGenericBuilder_RemoteStrict(t *testing.T) {
+	_, srv := newConfigServer(t, "application/json", `{"prot": 8082}`)
+
+	_, err := New(newTestConfig()).Env(MapEnv{}).Remote(Remote{URL: srv.URL}).Strict(StrictError).Build()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `unknown remote config key "prot" in `+srv.URL+", did you mean port?")
+}
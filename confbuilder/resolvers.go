@@ -0,0 +1,251 @@
+package confbuilder
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// SecretResolver resolves the reference of ${scheme:reference} placeholders found in
+// configuration file and env values
+type SecretResolver interface {
+	Resolve(ref string) (string, error)
+}
+
+// ResolverFunc adapts a function to the SecretResolver interface
+type ResolverFunc func(ref string) (string, error)
+
+// Resolve calls f(ref)
+func (f ResolverFunc) Resolve(ref string) (string, error) {
+	return f(ref)
+}
+
+// Resolver registers r for ${scheme:...} placeholders, e.g. Resolver("env", EnvResolver{})
+// resolves ${env:HOME}. Placeholders are only expanded once at least one resolver is registered
+func (b *Builder[T]) Resolver(scheme string, r SecretResolver) *Builder[T] {
+	if b.resolvers == nil {
+		b.resolvers = map[string]SecretResolver{}
+	}
+	b.resolvers[scheme] = r
+	return b
+}
+
+// EnvResolver resolves references to environment variables. Registered on a builder, it looks
+// them up in the loaded .env files and the env source, following their precedence. Used alone,
+// it reads the process environment
+type EnvResolver struct{}
+
+// Resolve returns the value of the environment variable ref
+func (EnvResolver) Resolve(ref string) (string, error) {
+	return lookupEnvRef(OSEnv{}, ref)
+}
+
+// lookupEnvRef returns the value of the variable ref of env, failing when it is not set
+func lookupEnvRef(env EnvSource, ref string) (string, error) {
+	value, ok := env.Lookup(ref)
+	if !ok {
+		return "", fmt.Errorf("environment variable %s is not set", ref)
+	}
+	return value, nil
+}
+
+// FileResolver resolves references to files inside Dir, such as /run/secrets
+type FileResolver struct {
+	Dir string
+}
+
+// Resolve returns the content of the file ref without its trailing newline
+func (r FileResolver) Resolve(ref string) (string, error) {
+	if !filepath.IsLocal(ref) {
+		return "", fmt.Errorf("secret file %s is outside of %s", ref, r.Dir)
+	}
+	data, err := os.ReadFile(filepath.Join(r.Dir, ref))
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
+
+// MapResolver resolves references from an in-memory map, mainly useful in tests
+type MapResolver map[string]string
+
+// Resolve returns the value stored under ref
+func (m MapResolver) Resolve(ref string) (string, error) {
+	value, ok := m[ref]
+	if !ok {
+		return "", fmt.Errorf("secret %s not found", ref)
+	}
+	return value, nil
+}
+
+// EncryptedStore is a local secret store kept in a single AES-256-GCM encrypted file
+type EncryptedStore struct {
+	secrets map[string]string
+}
+
+// OpenEncryptedStore decrypts the store at path with a 32 bytes key
+func OpenEncryptedStore(path string, key []byte) (*EncryptedStore, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read secret store: %w", err)
+	}
+
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < gcm.NonceSize() {
+		return nil, fmt.Errorf("failed to decrypt secret store: file is too short")
+	}
+	plain, err := gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt secret store: %w", err)
+	}
+
+	store := &EncryptedStore{}
+	if err := json.Unmarshal(plain, &store.secrets); err != nil {
+		return nil, fmt.Errorf("failed to parse secret store: %w", err)
+	}
+	return store, nil
+}
+
+// WriteEncryptedStore encrypts secrets with a 32 bytes key and writes them to path
+func WriteEncryptedStore(path string, key []byte, secrets map[string]string) error {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return err
+	}
+
+	plain, err := json.Marshal(secrets)
+	if err != nil {
+		return fmt.Errorf("failed to encode secret store: %w", err)
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	return os.WriteFile(path, gcm.Seal(nonce, nonce, plain, nil), 0600)
+}
+
+// Resolve returns the secret stored under ref
+func (s *EncryptedStore) Resolve(ref string) (string, error) {
+	value, ok := s.secrets[ref]
+	if !ok {
+		return "", fmt.Errorf("secret %s not found", ref)
+	}
+	return value, nil
+}
+
+// newGCM returns the AES-GCM cipher used by encrypted stores
+func newGCM(key []byte) (cipher.AEAD, error) {
+	if len(key) != 32 {
+		return nil, fmt.Errorf("secret store key must be 32 bytes, got %d", len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// interpolate replaces ${scheme:ref} placeholders in s with the value returned by the resolver
+// registered for scheme. "$${" escapes a literal "${" and placeholders without a scheme are kept
+func interpolate(s string, resolvers map[string]SecretResolver) (string, error) {
+	if len(resolvers) == 0 || !strings.Contains(s, "${") {
+		return s, nil
+	}
+
+	var sb strings.Builder
+	for {
+		start := strings.Index(s, "${")
+		if start < 0 {
+			sb.WriteString(s)
+			return sb.String(), nil
+		}
+
+		// Escaped placeholder
+		if start > 0 && s[start-1] == '$' {
+			sb.WriteString(s[:start-1])
+			sb.WriteString("${")
+			s = s[start+2:]
+			continue
+		}
+
+		end := strings.IndexByte(s[start:], '}')
+		if end < 0 {
+			sb.WriteString(s)
+			return sb.String(), nil
+		}
+		end += start
+
+		sb.WriteString(s[:start])
+		placeholder := s[start : end+1]
+		scheme, ref, ok := strings.Cut(s[start+2:end], ":")
+		if !ok {
+			sb.WriteString(placeholder)
+		} else {
+			resolver, found := resolvers[scheme]
+			if !found {
+				return "", fmt.Errorf("no resolver registered for %s", placeholder)
+			}
+			value, err := resolver.Resolve(ref)
+			if err != nil {
+				return "", fmt.Errorf("failed to resolve %s: %w", placeholder, err)
+			}
+			sb.WriteString(value)
+		}
+		s = s[end+1:]
+	}
+}
+
+// interpolateTree replaces placeholders in every string of a configuration file tree
+func interpolateTree(tree any, resolvers map[string]SecretResolver, path string) (any, error) {
+	if len(resolvers) == 0 {
+		return tree, nil
+	}
+
+	switch t := tree.(type) {
+	case string:
+		value, err := interpolate(t, resolvers)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		return value, nil
+
+	case map[string]any:
+		keys := make([]string, 0, len(t))
+		for k := range t {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys) // Deterministic error reporting
+		for _, k := range keys {
+			value, err := interpolateTree(t[k], resolvers, joinPath(path, k))
+			if err != nil {
+				return nil, err
+			}
+			t[k] = value
+		}
+		return t, nil
+
+	case []any:
+		for i, v := range t {
+			value, err := interpolateTree(v, resolvers, path+"["+strconv.Itoa(i)+"]")
+			if err != nil {
+				return nil, err
+			}
+			t[i] = value
+		}
+		return t, nil
+
+	default:
+		return tree, nil
+	}
+}
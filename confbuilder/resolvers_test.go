@@ -0,0 +1,184 @@
+package confbuilder
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenericBuilder_Resolvers(t *testing.T) {
+	dir := t.TempDir()
+	secretsDir := t.TempDir()
+	writeFile(t, secretsDir, "db_user", "fileuser\n")
+
+	key := bytes.Repeat([]byte{7}, 32)
+	storePath := filepath.Join(dir, "secrets.enc")
+	require.NoError(t, WriteEncryptedStore(storePath, key, map[string]string{"db/password": "store-pass-123"}))
+	store, err := OpenEncryptedStore(storePath, key)
+	require.NoError(t, err)
+
+	configPath := writeFile(t, dir, "config.json", `{
+		"app_name": "${vault:app/name}",
+		"tags": ["${env:RESOLVER_TAG}", "static"],
+		"database": {
+			"password": "${secret:db/password}",
+			"username": "${file:db_user}"
+		}
+	}`)
+
+	setEnvVars(t, map[string]string{
+		"RESOLVER_TAG": "from-env",
+		"TEST_DB_HOST": "${vault:db/host}",
+		"TEST_DB_PORT": "${vault:db/port}",
+	})
+
+	cfg, err := New(newTestConfig()).
+		EnvPrefix("TEST_").
+		File(&configPath).
+		Resolver("env", EnvResolver{}).
+		Resolver("file", FileResolver{Dir: secretsDir}).
+		Resolver("secret", store).
+		Resolver("vault", MapResolver{"app/name": "vault-app", "db/host": "vault-db", "db/port": "6543"}).
+		Build()
+	require.NoError(t, err)
+
+	assert.Equal(t, "vault-app", cfg.AppName)
+	assert.Equal(t, []string{"from-env", "static"}, cfg.Tags)
+	assert.Equal(t, "store-pass-123", cfg.Database.Password)
+	assert.Equal(t, "fileuser", cfg.Database.Username)
+	assert.Equal(t, "vault-db", cfg.Database.Host)
+	assert.Equal(t, 6543, cfg.Database.Port) // Resolved before type conversion
+}
+
+func TestGenericBuilder_ResolversErrors(t *testing.T) {
+	t.Run("unknown scheme in env", func(t *testing.T) {
+		setEnvVars(t, map[string]string{"TEST_DB_HOST": "${vault:db/host}"})
+		_, err := New(newTestConfig()).EnvPrefix("TEST_").Resolver("env", EnvResolver{}).Build()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to resolve TEST_DB_HOST: no resolver registered for ${vault:db/host}")
+	})
+
+	t.Run("failing resolver in file", func(t *testing.T) {
+		configPath := writeFile(t, t.TempDir(), "config.json", `{"database": {"password": "${secret:missing}"}}`)
+		_, err := New(newTestConfig()).File(&configPath).Resolver("secret", MapResolver{}).Build()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to resolve config file value: database.password: failed to resolve ${secret:missing}: secret missing not found")
+	})
+
+	t.Run("custom resolver error is wrapped", func(t *testing.T) {
+		errBackend := errors.New("backend down")
+		setEnvVars(t, map[string]string{"TEST_DB_HOST": "${vault:db/host}"})
+		_, err := New(newTestConfig()).
+			EnvPrefix("TEST_").
+			Resolver("vault", ResolverFunc(func(string) (string, error) { return "", errBackend })).
+			Build()
+		require.Error(t, err)
+		assert.True(t, errors.Is(err, errBackend))
+	})
+
+	t.Run("placeholders ignored without resolvers", func(t *testing.T) {
+		setEnvVars(t, map[string]string{"TEST_DB_HOST": "${vault:db/host}"})
+		_, err := New(newTestConfig()).EnvPrefix("TEST_").Build()
+		require.Error(t, err) // The literal placeholder is not a valid hostname
+		assert.Contains(t, err.Error(), "invalid configuration")
+	})
+}
+
+func TestGenericBuilder_EnvResolverSource(t *testing.T) {
+	t.Run("Env source", func(t *testing.T) {
+		cfg, err := New(newTestConfig()).
+			EnvPrefix("TEST_").
+			Env(MapEnv{"TEST_APP_NAME": "${env:NAME}", "NAME": "from-map"}).
+			Resolver("env", EnvResolver{}).
+			Build()
+		require.NoError(t, err)
+		assert.Equal(t, "from-map", cfg.AppName)
+	})
+
+	t.Run(".env files", func(t *testing.T) {
+		dir := t.TempDir()
+		writeFile(t, dir, ".env", "NAME=from-dotenv\n")
+		originalWD, err := os.Getwd()
+		require.NoError(t, err)
+		require.NoError(t, os.Chdir(dir))
+		t.Cleanup(func() { os.Chdir(originalWD) })
+
+		cfg, err := New(newTestConfig()).
+			EnvPrefix("TEST_").
+			EnvFiles(".env").
+			Env(MapEnv{"TEST_APP_NAME": "${env:NAME}"}).
+			Resolver("env", EnvResolver{}).
+			Build()
+		require.NoError(t, err)
+		assert.Equal(t, "from-dotenv", cfg.AppName)
+	})
+
+	t.Run("Missing variable", func(t *testing.T) {
+		_, err := New(newTestConfig()).
+			EnvPrefix("TEST_").
+			Env(MapEnv{"TEST_APP_NAME": "${env:NAME}"}).
+			Resolver("env", EnvResolver{}).
+			Build()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "environment variable NAME is not set")
+	})
+}
+
+func TestInterpolate(t *testing.T) {
+	resolvers := map[string]SecretResolver{"m": MapResolver{"a": "1", "b": "2"}}
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"plain", "plain"},
+		{"${m:a}", "1"},
+		{"x-${m:a}-${m:b}-y", "x-1-2-y"},
+		{"$${m:a}", "${m:a}"},
+		{"${HOME}", "${HOME}"},
+		{"${m:a", "${m:a"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			value, err := interpolate(tt.input, resolvers)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, value)
+		})
+	}
+}
+
+func TestFileResolver_OutsideDir(t *testing.T) {
+	_, err := FileResolver{Dir: t.TempDir()}.Resolve("../etc/passwd")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "outside of")
+}
+
+func TestEncryptedStore(t *testing.T) {
+	dir := t.TempDir()
+	key := bytes.Repeat([]byte{1}, 32)
+	path := filepath.Join(dir, "store.enc")
+	require.NoError(t, WriteEncryptedStore(path, key, map[string]string{"a": "b"}))
+
+	store, err := OpenEncryptedStore(path, key)
+	require.NoError(t, err)
+	value, err := store.Resolve("a")
+	require.NoError(t, err)
+	assert.Equal(t, "b", value)
+
+	_, err = store.Resolve("missing")
+	assert.Error(t, err)
+
+	_, err = OpenEncryptedStore(path, bytes.Repeat([]byte{2}, 32))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to decrypt secret store")
+
+	err = WriteEncryptedStore(path, []byte("short"), nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "must be 32 bytes")
+}
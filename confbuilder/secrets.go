@@ -0,0 +1,29 @@
+package confbuilder
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+const (
+	// fileSuffix marks env variables holding the path of a file with the actual value
+	fileSuffix = "_FILE"
+	// fileScheme marks env values of secret fields referencing a file with the actual value
+	fileScheme = "file://"
+)
+
+// readSecretFile reads a mounted secret file referenced by the env variable name, trimming
+// the trailing newline most tools append when writing secrets
+func readSecretFile(name, path string, origin Origin) (string, Origin, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", origin, fmt.Errorf("failed to read secret file for %s: %w", name, err)
+	}
+
+	// Keep the .env file as origin when the reference itself came from one
+	if origin.File == "" {
+		origin.File = path
+	}
+	return strings.TrimRight(string(data), "\r\n"), origin, nil
+}
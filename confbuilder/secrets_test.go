@@ -0,0 +1,95 @@
+package confbuilder
+
+import (
+	"net/url"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenericBuilder_SecretFiles(t *testing.T) {
+	dir := t.TempDir()
+	passwordFile := writeFile(t, dir, "db_password", "file-secret-123\n")
+	portFile := writeFile(t, dir, "db_port", "6543\r\n")
+	nameFile := writeFile(t, dir, "app_name", "secret-app")
+
+	setEnvVars(t, map[string]string{
+		"TEST_DB_PASSWORD_FILE": passwordFile,
+		"TEST_DB_PORT_FILE":     portFile,
+		"TEST_APP_NAME":         "env-app", // Plain variables win over _FILE ones
+		"TEST_APP_NAME_FILE":    nameFile,
+	})
+
+	cfg, report, err := New(newTestConfig()).EnvPrefix("TEST_").BuildWithReport()
+	require.NoError(t, err)
+
+	assert.Equal(t, "file-secret-123", cfg.Database.Password)
+	assert.Equal(t, 6543, cfg.Database.Port)
+	assert.Equal(t, "env-app", cfg.AppName)
+
+	assert.Equal(t, Origin{Source: SourceEnv, Key: "TEST_DB_PASSWORD_FILE", File: passwordFile}, report.Provenance["Database.Password"])
+}
+
+// SecretFileConfig mixes secret and plain fields receiving file:// values
+type SecretFileConfig struct {
+	Token  string  `env:"TOKEN" secret:"true"`
+	Backup string  `env:"BACKUP"`
+	Hook   url.URL `env:"HOOK"`
+}
+
+func TestGenericBuilder_SecretFileURLs(t *testing.T) {
+	tokenFile := writeFile(t, t.TempDir(), "token", "t0k3n\n")
+	env := MapEnv{
+		"SEC_TOKEN":  "file://" + tokenFile,
+		"SEC_BACKUP": "file:///tmp", // Only secret fields read file:// values
+		"SEC_HOOK":   "file:///var/run/hook.sock",
+	}
+
+	cfg, report, err := New(&SecretFileConfig{}).EnvPrefix("SEC_").Env(env).BuildWithReport()
+	require.NoError(t, err)
+	assert.Equal(t, "t0k3n", cfg.Token)
+	assert.Equal(t, "file:///tmp", cfg.Backup)
+	assert.Equal(t, "file:///var/run/hook.sock", cfg.Hook.String())
+	assert.Equal(t, Origin{Source: SourceEnv, Key: "SEC_TOKEN", File: tokenFile}, report.Provenance["Token"])
+}
+
+func TestGenericBuilder_SecretFilesErrors(t *testing.T) {
+	missing := filepath.Join(t.TempDir(), "missing")
+
+	t.Run("missing _FILE target", func(t *testing.T) {
+		setEnvVars(t, map[string]string{"TEST_DB_PASSWORD_FILE": missing})
+		_, err := New(newTestConfig()).EnvPrefix("TEST_").Build()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to read secret file for TEST_DB_PASSWORD_FILE")
+	})
+
+	t.Run("missing file:// target", func(t *testing.T) {
+		_, err := New(&SecretFileConfig{}).EnvPrefix("SEC_").Env(MapEnv{"SEC_TOKEN": "file://" + missing}).Build()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to read secret file for SEC_TOKEN")
+	})
+
+	t.Run("invalid content reports variable", func(t *testing.T) {
+		portFile := writeFile(t, t.TempDir(), "port", "not-a-port\n")
+		setEnvVars(t, map[string]string{"TEST_PORT_FILE": portFile})
+		_, err := New(newTestConfig()).EnvPrefix("TEST_").Build()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "invalid integer value for TEST_PORT_FILE")
+	})
+}
+
+func TestGenericBuilder_SecretFilesDisabled(t *testing.T) {
+	passwordFile := writeFile(t, t.TempDir(), "db_password", "file-secret-123\n")
+	setEnvVars(t, map[string]string{
+		"TEST_DB_PASSWORD_FILE": passwordFile,
+		"TEST_DB_USERNAME":      "file://literal",
+	})
+
+	cfg, err := New(newTestConfig()).EnvPrefix("TEST_").SecretFiles(false).Build()
+	require.NoError(t, err)
+
+	assert.Equal(t, "testpass123", cfg.Database.Password)
+	assert.Equal(t, "file://literal", cfg.Database.Username)
+}
@@ -0,0 +1,250 @@
+package confbuilder
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// Source is one layer of the configuration pipeline. Sources run in order once the defaults
+// are applied and before validation, every source overriding the values set by the previous ones
+type Source interface {
+	Load(l *Layer) error
+}
+
+// SourceFunc adapts a function to the Source interface
+type SourceFunc func(l *Layer) error
+
+// Load implements Source
+func (f SourceFunc) Load(l *Layer) error {
+	return f(l)
+}
+
+// Layer gives sources access to the configuration being built and to the builder settings
+type Layer struct {
+	target  any
+	profile string
+	report  *Report
+	errs    *ConfigError // Collects invalid values of every source
+
+	prefix      string
+	naming      envNaming
+	decoders    decoders
+	resolvers   map[string]SecretResolver // EnvResolver bound to the layer by bindResolvers
+	env         EnvSource
+	dotenv      MapEnv // Variables of the .env files loaded so far
+	allowEmpty  bool
+	secretFiles bool
+	fileLayers  []FileLayer   // Builder File and Files layers, without their profile variants
+	envSearch   envFileSearch // Builder .env settings, names holding the EnvFiles names
+	flagOutput  io.Writer
+
+	files   []loadedFile // Trees applied so far, checked by strict mode
+	loaders []*envLoader // Env layers applied so far, checked by strict mode
+}
+
+// Target returns a pointer to the configuration being built
+func (l *Layer) Target() any {
+	return l.target
+}
+
+// Profile returns the active profile, empty when there is none
+func (l *Layer) Profile() string {
+	return l.profile
+}
+
+// Env returns the env source of the builder, the process environment by default
+func (l *Layer) Env() EnvSource {
+	return l.env
+}
+
+// bindResolvers returns resolvers with EnvResolver replaced by a resolver looking variables up
+// in the .env files loaded so far, then in the env source. The .env values only hold variables
+// winning over the env source, see loadEnvFromAncestors
+func (l *Layer) bindResolvers(resolvers map[string]SecretResolver) map[string]SecretResolver {
+	bound := make(map[string]SecretResolver, len(resolvers))
+	for scheme, r := range resolvers {
+		if _, ok := r.(EnvResolver); ok {
+			r = ResolverFunc(func(ref string) (string, error) {
+				if value, ok := l.dotenv[ref]; ok {
+					return value, nil
+				}
+				return lookupEnvRef(l.env, ref)
+			})
+		}
+		bound[scheme] = r
+	}
+	return bound
+}
+
+// Watch adds path to the files whose changes make watchers reload the configuration
+func (l *Layer) Watch(path string) {
+	if path == "" {
+		return
+	}
+	l.report.watchFiles = append(l.report.watchFiles, path)
+}
+
+// ApplyTree decodes a tree of maps, slices and scalars keyed like the json tags, as produced
+// by decoding a JSON document into an any, over the configuration. origin is recorded as the
+// provenance of the fields the tree sets
+func (l *Layer) ApplyTree(tree any, origin Origin) error {
+	return l.applyFile(loadedFile{path: origin.File, tree: tree, source: origin.Source})
+}
+
+// applyFile decodes a parsed configuration document over the configuration
+func (l *Layer) applyFile(file loadedFile) error {
+	return l.applyFiles([]loadedFile{file})
+}
+
+// applyFiles decodes parsed configuration documents in order. encoding/json replaces map
+// elements as a whole, so every document is deep merged into the previous ones before being
+// decoded, overriding only the keys it sets
+func (l *Layer) applyFiles(files []loadedFile) error {
+	var merged any
+	for _, file := range files {
+		if err := recordTree(l.target, l.naming, file, l.report.Provenance); err != nil {
+			return err
+		}
+		l.files = append(l.files, file)
+
+		// Resolving and decoding consume the tree, the caller may hold it across builds
+		tree, err := interpolateTree(copyTree(file.tree), l.resolvers, "")
+		if err != nil {
+			return fmt.Errorf("failed to resolve config file value: %w", err)
+		}
+		merged = mergeTrees(merged, tree)
+
+		decoded := copyTree(merged)
+		if err := l.decoders.decodeTree(l.target, decoded, l.naming); err != nil {
+			return file.parseError(err)
+		}
+		if err := applyTree(l.target, decoded); err != nil {
+			return file.parseError(err)
+		}
+	}
+	return nil
+}
+
+// ApplyEnv loads the variables of env bound to fields, with the builder prefix, over the
+// configuration. Their provenance is recorded with source, e.g. SourceEnv. Invalid values are
+// collected and reported by Build together with the validation errors
+func (l *Layer) ApplyEnv(env EnvSource, source SourceKind) error {
+	return l.applyEnv(env, source, nil)
+}
+
+// applyEnv loads the variables of env, those in envFileKeys being attributed to their .env file
+func (l *Layer) applyEnv(env EnvSource, source SourceKind, envFileKeys map[string]string) error {
+	loader := &envLoader{
+		prefix:      l.prefix,
+		naming:      l.naming,
+		env:         env,
+		source:      source,
+		allowEmpty:  l.allowEmpty,
+		secretFiles: l.secretFiles,
+		resolvers:   l.resolvers,
+		decoders:    l.decoders,
+		envFileKeys: envFileKeys,
+		provenance:  l.report.Provenance,
+		errs:        l.errs,
+	}
+	l.loaders = append(l.loaders, loader)
+	return loader.loadEnvToStruct(l.target)
+}
+
+// FromFiles returns the source decoding configuration files deep merged in order, the File and
+// Files layers of the builder when no layer is given. Every layer is followed by its optional
+// profile variant
+func FromFiles(layers ...FileLayer) Source {
+	return SourceFunc(func(l *Layer) error {
+		selected := layers
+		if len(selected) == 0 {
+			selected = l.fileLayers
+		}
+		selected = profileLayers(selected, l.profile)
+		for _, layer := range selected {
+			l.Watch(layer.Path)
+		}
+
+		files, err := loadFileLayers(selected)
+		if err != nil {
+			return err
+		}
+		return l.applyFiles(files)
+	})
+}
+
+// FromRemote returns the source fetching a configuration document over HTTP, see Remote. The
+// source keeps the last good document, reuse it across builds to benefit from ETag caching
+func FromRemote(r Remote) Source {
+	return &remoteSource{config: r}
+}
+
+// Load implements Source
+func (s *remoteSource) Load(l *Layer) error {
+	file, err := s.load(context.Background())
+	if err != nil {
+		return err
+	}
+	return l.applyFile(file)
+}
+
+// FromDotenv returns the source loading .env files, named by names or by the EnvFiles of the
+// builder when none is given, discovered and layered following the builder settings. With
+// ProcessEnvWins, variables set in the env source are skipped
+func FromDotenv(names ...string) Source {
+	return SourceFunc(func(l *Layer) error {
+		search := l.envSearch
+		if len(names) > 0 {
+			search.names = names
+		}
+		search.names = profileEnvFiles(search.names, l.profile)
+
+		files, dotenv, envFileKeys, err := loadEnvFromAncestors(l.env, search)
+		if err != nil {
+			return fmt.Errorf("failed to load environment variables: %w", err)
+		}
+		l.report.EnvFiles = append(l.report.EnvFiles, files...)
+		for key, value := range dotenv {
+			l.dotenv[key] = value
+		}
+		for _, file := range files {
+			l.Watch(file)
+		}
+
+		// .env files may also be created later in the working directory
+		if cwd, err := os.Getwd(); err == nil {
+			for _, name := range search.names {
+				l.Watch(filepath.Join(cwd, name))
+			}
+		}
+
+		if err := l.applyEnv(dotenv, SourceEnvFile, envFileKeys); err != nil {
+			return fmt.Errorf("failed to override configuration from environment: %w", err)
+		}
+		return nil
+	})
+}
+
+// FromEnv returns the source loading the variables of the builder env source
+func FromEnv() Source {
+	return SourceFunc(func(l *Layer) error {
+		if err := l.ApplyEnv(l.env, SourceEnv); err != nil {
+			return fmt.Errorf("failed to override configuration from environment: %w", err)
+		}
+		return nil
+	})
+}
+
+// FromFlags returns the source parsing command-line flags from args, one flag per env bound
+// field, e.g. --db-port for DB_PORT. Only the flags present in args override other sources
+func FromFlags(args []string) Source {
+	return SourceFunc(func(l *Layer) error {
+		if err := loadFlagsToStruct(l.target, l.prefix, l.naming, l.decoders, args, l.flagOutput, l.report.Provenance); err != nil {
+			return fmt.Errorf("failed to parse command-line flags: %w", err)
+		}
+		return nil
+	})
+}
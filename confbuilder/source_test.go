@@ -0,0 +1,179 @@
+package confbuilder
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// sourceVault marks values of a team specific source reading a secret store
+const sourceVault SourceKind = "vault"
+
+func TestGenericBuilder_Sources(t *testing.T) {
+	dir := t.TempDir()
+	configPath := writeFile(t, dir, "config.json", `{"port": 8081, "app_name": "from-file"}`)
+
+	env := MapEnv{"SRC_PORT": "9090", "SRC_APP_NAME": "from-env"}
+	vault := SourceFunc(func(l *Layer) error {
+		return l.ApplyEnv(MapEnv{"SRC_APP_NAME": "from-vault"}, sourceVault)
+	})
+	overrides := SourceFunc(func(l *Layer) error {
+		return l.ApplyTree(map[string]any{"enabled": true, "timeout": "1m"}, Origin{Source: "overrides", File: "inline"})
+	})
+
+	tests := []struct {
+		name     string
+		sources  func(b *Builder[*TestConfig]) []Source
+		port     int
+		appName  string
+		origins  map[string]Origin
+		watching []string
+	}{
+		{
+			name:    "Default order",
+			sources: func(b *Builder[*TestConfig]) []Source { return nil },
+			port:    9090, appName: "from-env",
+			origins:  map[string]Origin{"Port": {Source: SourceEnv, Key: "SRC_PORT"}},
+			watching: []string{configPath},
+		},
+		{
+			name:    "Files win over env",
+			sources: func(b *Builder[*TestConfig]) []Source { return []Source{FromEnv(), FromFiles()} },
+			port:    8081, appName: "from-file",
+			origins: map[string]Origin{"Port": {Source: SourceFile, File: configPath}},
+		},
+		{
+			name:    "Explicit file layers",
+			sources: func(b *Builder[*TestConfig]) []Source { return []Source{FromFiles(RequiredFile(configPath))} },
+			port:    8081, appName: "from-file",
+		},
+		{
+			name: "Custom sources appended",
+			sources: func(b *Builder[*TestConfig]) []Source {
+				return append(b.DefaultSources(), vault, overrides)
+			},
+			port: 9090, appName: "from-vault",
+			origins: map[string]Origin{
+				"AppName": {Source: sourceVault, Key: "SRC_APP_NAME"},
+				"Enabled": {Source: "overrides", File: "inline"},
+			},
+		},
+		{
+			name:    "Flags only",
+			sources: func(b *Builder[*TestConfig]) []Source { return []Source{FromFlags([]string{"--port", "7070"})} },
+			port:    7070, appName: "test-app",
+			origins: map[string]Origin{"Port": {Source: SourceFlag, Key: "--port"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := New(newTestConfig()).EnvPrefix("SRC_").Env(env).File(&configPath)
+			if sources := tt.sources(b); sources != nil {
+				b.Sources(sources...)
+			}
+
+			cfg, report, err := b.BuildWithReport()
+			require.NoError(t, err)
+			assert.Equal(t, tt.port, cfg.Port)
+			assert.Equal(t, tt.appName, cfg.AppName)
+			for path, origin := range tt.origins {
+				assert.Equal(t, origin, report.Provenance[path], path)
+			}
+			for _, file := range tt.watching {
+				assert.Contains(t, report.watchFiles, file)
+			}
+		})
+	}
+}
+
+func TestFromDotenv(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, ".env.source", "SRC_PORT=7070\nSRC_APP_NAME=dotenv-app\n")
+
+	originalWD, err := os.Getwd()
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(dir))
+	t.Cleanup(func() { os.Chdir(originalWD) })
+
+	// The source is tested alone, the env source and its precedence still apply
+	cfg, report, err := New(newTestConfig()).
+		EnvPrefix("SRC_").
+		Env(MapEnv{"SRC_APP_NAME": "from-env"}).
+		Sources(FromDotenv(".env.source")).
+		BuildWithReport()
+	require.NoError(t, err)
+	assert.Equal(t, 7070, cfg.Port)
+	assert.Equal(t, "test-app", cfg.AppName)
+	assert.Equal(t, []string{filepath.Join(dir, ".env.source")}, report.EnvFiles)
+	assert.Equal(t, Origin{Source: SourceEnvFile, Key: "SRC_PORT", File: filepath.Join(dir, ".env.source")}, report.Provenance["Port"])
+}
+
+func TestLayer_Errors(t *testing.T) {
+	t.Run("Invalid values are collected", func(t *testing.T) {
+		_, err := New(newTestConfig()).
+			EnvPrefix("SRC_").
+			Sources(SourceFunc(func(l *Layer) error {
+				return l.ApplyEnv(MapEnv{"SRC_PORT": "abc", "SRC_TIMEOUT": "soon"}, sourceVault)
+			})).
+			Build()
+
+		var cfgErr *ConfigError
+		require.ErrorAs(t, err, &cfgErr)
+		require.Len(t, cfgErr.Fields, 2)
+		assert.Equal(t, sourceVault, cfgErr.Fields[0].Source)
+	})
+
+	t.Run("Strict mode checks custom sources", func(t *testing.T) {
+		_, err := New(newTestConfig()).
+			EnvPrefix("SRC_").
+			Strict(StrictError).
+			Sources(SourceFunc(func(l *Layer) error {
+				return l.ApplyEnv(MapEnv{"SRC_PROT": "8080"}, sourceVault)
+			})).
+			Build()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "unknown env variable SRC_PROT, did you mean SRC_PORT?")
+	})
+
+	t.Run("Strict mode checks custom trees", func(t *testing.T) {
+		_, err := New(newTestConfig()).
+			Strict(StrictError).
+			Sources(SourceFunc(func(l *Layer) error {
+				return l.ApplyTree(map[string]any{"prot": 8080}, Origin{Source: "overrides", File: "inline"})
+			})).
+			Build()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), `unknown overrides config key "prot" in inline, did you mean port?`)
+	})
+
+	t.Run("Trees are left untouched", func(t *testing.T) {
+		tree := map[string]any{"timeout": "1m", "app_name": "${m:name}"}
+		b := New(newTestConfig()).
+			Env(MapEnv{}).
+			Resolver("m", MapResolver{"name": "resolved"}).
+			Sources(SourceFunc(func(l *Layer) error {
+				return l.ApplyTree(tree, Origin{Source: "overrides", File: "inline"})
+			}))
+
+		for range 2 {
+			cfg, err := b.Build()
+			require.NoError(t, err)
+			assert.Equal(t, time.Minute, cfg.Timeout)
+			assert.Equal(t, "resolved", cfg.AppName)
+		}
+		assert.Equal(t, map[string]any{"timeout": "1m", "app_name": "${m:name}"}, tree)
+	})
+
+	t.Run("Source failures stop the build", func(t *testing.T) {
+		_, err := New(newTestConfig()).
+			Sources(FromFiles(RequiredFile(filepath.Join(t.TempDir(), "missing.json")))).
+			Build()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to read config file")
+	})
+}
@@ -0,0 +1,77 @@
+package confbuilder
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// Store holds the current configuration, readers never block while writers swap it at runtime
+type Store[T any] struct {
+	current atomic.Pointer[storeEntry[T]]
+
+	mu    sync.Mutex // Serialises writers so that hooks observe versions in order
+	hooks []func(old, new T)
+}
+
+// storeEntry pairs a configuration with its version so both are read atomically
+type storeEntry[T any] struct {
+	config  T
+	version uint64
+}
+
+// NewStore returns a store holding cfg as version 1
+func NewStore[T any](cfg T) *Store[T] {
+	s := &Store[T]{}
+	s.current.Store(&storeEntry[T]{config: cfg, version: 1})
+	return s
+}
+
+// BuildStore builds the configuration and returns a store holding it
+func (b *Builder[T]) BuildStore() (*Store[T], error) {
+	cfg, err := b.Build()
+	if err != nil {
+		return nil, err
+	}
+	return NewStore(cfg), nil
+}
+
+// Load returns the current configuration without locking, safe to call on hot paths. The
+// returned value must be treated as read-only since other goroutines share it
+func (s *Store[T]) Load() T {
+	return s.current.Load().config
+}
+
+// Version returns the version of the current configuration, incremented on every Swap
+func (s *Store[T]) Version() uint64 {
+	return s.current.Load().version
+}
+
+// Snapshot returns the current configuration together with its version
+func (s *Store[T]) Snapshot() (T, uint64) {
+	entry := s.current.Load()
+	return entry.config, entry.version
+}
+
+// Swap replaces the current configuration, runs the OnChange hooks in registration order and
+// returns the new version
+func (s *Store[T]) Swap(cfg T) uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	old := s.current.Load()
+	entry := &storeEntry[T]{config: cfg, version: old.version + 1}
+	s.current.Store(entry)
+
+	for _, hook := range s.hooks {
+		hook(old.config, cfg)
+	}
+	return entry.version
+}
+
+// OnChange registers fn to be called after every Swap, hooks run in registration order on the
+// swapping goroutine and must not call Swap themselves
+func (s *Store[T]) OnChange(fn func(old, new T)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.hooks = append(s.hooks, fn)
+}
@@ -0,0 +1,88 @@
+package confbuilder
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStore(t *testing.T) {
+	s := NewStore(TestConfig{Port: 1})
+	cfg, version := s.Snapshot()
+	assert.Equal(t, 1, cfg.Port)
+	assert.Equal(t, uint64(1), version)
+
+	var calls []string
+	s.OnChange(func(old, new TestConfig) {
+		calls = append(calls, "first")
+		assert.Equal(t, 1, old.Port)
+		assert.Equal(t, 2, new.Port)
+	})
+	s.OnChange(func(old, new TestConfig) {
+		calls = append(calls, "second")
+		assert.Equal(t, 2, s.Load().Port) // Hooks run after the swap is visible
+	})
+
+	assert.Equal(t, uint64(2), s.Swap(TestConfig{Port: 2}))
+	assert.Equal(t, 2, s.Load().Port)
+	assert.Equal(t, uint64(2), s.Version())
+	assert.Equal(t, []string{"first", "second"}, calls)
+}
+
+func TestStore_ConcurrentAccess(t *testing.T) {
+	s := NewStore(&TestConfig{Port: 0})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(2)
+		go func(port int) {
+			defer wg.Done()
+			s.Swap(&TestConfig{Port: port})
+		}(i + 1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				cfg, version := s.Snapshot()
+				assert.NotNil(t, cfg)
+				assert.GreaterOrEqual(t, version, uint64(1))
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, uint64(9), s.Version())
+}
+
+func TestGenericBuilder_BuildStore(t *testing.T) {
+	s, err := New(newTestConfig()).BuildStore()
+	require.NoError(t, err)
+	assert.Equal(t, "test-app", s.Load().AppName)
+	assert.Equal(t, uint64(1), s.Version())
+
+	defaultCfg := newTestConfig()
+	defaultCfg.AppName = ""
+	_, err = New(defaultCfg).BuildStore()
+	require.Error(t, err)
+}
+
+func TestWatcher_Store(t *testing.T) {
+	dir := t.TempDir()
+	configPath := writeFile(t, dir, "config.json", `{"port": 8081}`)
+
+	w, err := New(newTestConfig()).File(&configPath).Watch(context.Background())
+	require.NoError(t, err)
+	defer w.Close()
+
+	var seen []int
+	w.Store().OnChange(func(old, new *TestConfig) { seen = append(seen, new.Port) })
+
+	writeFile(t, dir, "config.json", `{"port": 8082}`)
+	require.NoError(t, w.Reload())
+
+	assert.Equal(t, 8082, w.Store().Load().Port)
+	assert.Equal(t, uint64(2), w.Store().Version())
+	assert.Equal(t, []int{8082}, seen)
+}
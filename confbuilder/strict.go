@@ -0,0 +1,279 @@
+package confbuilder
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// StrictMode controls how config file keys and env variables matching no field are reported
+type StrictMode int
+
+const (
+	// StrictOff ignores unknown keys and variables
+	StrictOff StrictMode = iota
+	// StrictWarn logs unknown keys and variables as warnings
+	StrictWarn
+	// StrictError fails the build with a ConfigError listing unknown keys and variables
+	StrictError
+)
+
+// ErrUnknownKey is wrapped by the field errors reporting unknown keys and variables
+var ErrUnknownKey = errors.New("unknown configuration key")
+
+// Strict sets how config file keys and env variables sharing the prefix that match no field
+// are reported, each report suggests the closest valid name
+func (b *Builder[T]) Strict(mode StrictMode) *Builder[T] {
+	b.strict = mode
+	return b
+}
+
+// keyNode describes the config file keys accepted at one level of the configuration
+type keyNode struct {
+	name     string              // Key as declared, used in suggestions
+	children map[string]*keyNode // Keyed by lowercased name, nil when anything is accepted below
+	elem     *keyNode            // Element of slices and maps of structs
+}
+
+// unknownKey describes a key or variable matching no field
+type unknownKey struct {
+	name       string
+	suggestion string
+	origin     Origin
+	envVar     bool // Set for env variables, unset for keys of configuration documents
+}
+
+// checkStrict reports the unknown keys of the config files and the unknown env variables,
+// reserved holding the variables read by the builder itself, e.g. the profile variable
+func checkStrict(mode StrictMode, target any, naming envNaming, files []loadedFile, loaders []*envLoader, reserved []string, errs *ConfigError) error {
+	if mode == StrictOff {
+		return nil
+	}
+
+	root, err := buildKeyTree(target, naming)
+	if err != nil {
+		return err
+	}
+
+	var unknown []unknownKey
+	for _, file := range files {
+		checkKeys(file.tree, root, "", func(key, suggestion string) {
+			unknown = append(unknown, unknownKey{
+				name:       key,
+				suggestion: suggestion,
+				origin:     Origin{Source: file.source, Key: key, File: file.path},
+			})
+		})
+	}
+
+	for _, l := range loaders {
+		vars, err := unknownEnv(target, naming, l, reserved)
+		if err != nil {
+			return err
+		}
+		unknown = append(unknown, vars...)
+	}
+
+	for _, u := range unknown {
+		var message string
+		switch {
+		case u.envVar:
+			message = fmt.Sprintf("unknown env variable %s", u.name)
+		case u.origin.Source == SourceFile:
+			message = fmt.Sprintf("unknown config file key %q in %s", u.name, u.origin.File)
+		default:
+			message = fmt.Sprintf("unknown %s config key %q in %s", u.origin.Source, u.name, u.origin.File)
+		}
+		if u.suggestion != "" {
+			message += fmt.Sprintf(", did you mean %s?", u.suggestion)
+		}
+
+		if mode == StrictWarn {
+			slog.Warn("Unknown configuration key", "key", u.name, "source", u.origin.Source, "file", u.origin.File, "suggestion", u.suggestion)
+			continue
+		}
+		fe := FieldError{
+			Path:    u.name,
+			Source:  u.origin.Source,
+			Message: message,
+			Err:     fmt.Errorf("%w: %s", ErrUnknownKey, u.name),
+		}
+		if u.envVar {
+			fe.EnvVar = u.name
+		}
+		errs.add(fe)
+	}
+	return nil
+}
+
+// buildKeyTree returns the config file keys accepted by target, following encoding/json rules
+func buildKeyTree(target any, naming envNaming) (*keyNode, error) {
+	root := &keyNode{children: map[string]*keyNode{}}
+	err := walkFields(target, naming, func(f fieldInfo) error {
+		if len(f.jsonPath) == 0 {
+			return nil
+		}
+
+		node := root
+		for _, key := range f.jsonPath {
+			child, ok := node.children[strings.ToLower(key)]
+			if !ok {
+				child = &keyNode{name: key, children: map[string]*keyNode{}}
+				node.children[strings.ToLower(key)] = child
+			}
+			node = child
+		}
+
+		// Leaves accept any value, collections of structs check their elements
+		node.children = nil
+		if t := f.value.Type(); isStructCollection(t) {
+			elemType := t.Elem()
+			if elemType.Kind() == reflect.Ptr {
+				elemType = elemType.Elem()
+			}
+			elem, err := buildKeyTree(reflect.New(elemType).Interface(), naming)
+			if err != nil {
+				return err
+			}
+			node.elem = elem
+		}
+		return nil
+	})
+	return root, err
+}
+
+// checkKeys calls report for every key of tree not accepted by node
+func checkKeys(tree any, node *keyNode, path string, report func(key, suggestion string)) {
+	if node.elem != nil {
+		switch values := tree.(type) {
+		case []any:
+			for i, v := range values {
+				checkKeys(v, node.elem, path+"["+strconv.Itoa(i)+"]", report)
+			}
+		case map[string]any:
+			for k, v := range values {
+				checkKeys(v, node.elem, path+"["+k+"]", report)
+			}
+		}
+		return
+	}
+
+	m, ok := tree.(map[string]any)
+	if !ok || node.children == nil {
+		return
+	}
+
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		child, ok := node.children[strings.ToLower(k)]
+		if ok {
+			checkKeys(m[k], child, joinPath(path, child.name), report)
+			continue
+		}
+
+		names := make([]string, 0, len(node.children))
+		for _, c := range node.children {
+			names = append(names, joinPath(path, c.name))
+		}
+		sort.Strings(names)
+		report(joinPath(path, k), closest(joinPath(path, k), names))
+	}
+}
+
+// unknownEnv returns the env variables sharing the loader prefix that match no field nor
+// reserved name
+func unknownEnv(target any, naming envNaming, l *envLoader, reserved []string) ([]unknownKey, error) {
+	// Without a prefix every variable of the process would be reported
+	if l.prefix == "" {
+		return nil, nil
+	}
+
+	known := map[string]bool{}
+	for _, name := range reserved {
+		known[name] = true
+	}
+	var names, collections []string
+	err := walkFields(target, naming, func(f fieldInfo) error {
+		if f.envKey == "" {
+			return nil
+		}
+		name := l.prefix + f.envKey
+		known[name] = true
+		names = append(names, name)
+		if isStructCollection(f.value.Type()) {
+			collections = append(collections, name+"_")
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var unknown []unknownKey
+	for _, name := range l.env.Keys() {
+		if !strings.HasPrefix(name, l.prefix) || known[name] {
+			continue
+		}
+		if l.secretFiles && known[strings.TrimSuffix(name, fileSuffix)] {
+			continue
+		}
+		if hasAnyPrefix(name, collections) {
+			continue
+		}
+		unknown = append(unknown, unknownKey{name: name, suggestion: closest(name, names), origin: l.origin(name), envVar: true})
+	}
+	sort.Slice(unknown, func(i, j int) bool { return unknown[i].name < unknown[j].name })
+	return unknown, nil
+}
+
+// hasAnyPrefix reports whether s starts with one of prefixes
+func hasAnyPrefix(s string, prefixes []string) bool {
+	for _, p := range prefixes {
+		if strings.HasPrefix(s, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// closest returns the candidate nearest to name, empty when none is close enough to be a typo
+func closest(name string, candidates []string) string {
+	best, bestDistance := "", len(name)/3+2
+	for _, c := range candidates {
+		if d := levenshtein(strings.ToLower(name), strings.ToLower(c)); d < bestDistance {
+			best, bestDistance = c, d
+		}
+	}
+	return best
+}
+
+// levenshtein returns the edit distance between a and b
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(rb)]
+}
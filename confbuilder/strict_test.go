@@ -0,0 +1,110 @@
+package confbuilder
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenericBuilder_Strict(t *testing.T) {
+	setEnvVars(t, map[string]string{
+		"TEST_DB_PROT":            "5433",
+		"TEST_PORT":               "8080",
+		"TEST_DB_PASSWORD_FILE":   "",
+		"TEST_COMPLETELY_UNKNOWN": "1",
+	})
+
+	configPath := writeFile(t, t.TempDir(), "config.json", `{
+		"app_name": "strict-app",
+		"databse": {"host": "db"},
+		"server": {"timout": 5},
+		"tags": ["a"]
+	}`)
+
+	_, err := New(newTestConfig()).EnvPrefix("TEST_").File(&configPath).Strict(StrictError).Build()
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, ErrUnknownKey))
+
+	var cfgErr *ConfigError
+	require.True(t, errors.As(err, &cfgErr))
+
+	messages := map[string]FieldError{}
+	for _, fe := range cfgErr.Fields {
+		messages[fe.Path] = fe
+	}
+	require.Len(t, messages, 4)
+
+	assert.Equal(t, `unknown config file key "databse" in `+configPath+`, did you mean database?`, messages["databse"].Message)
+	assert.Equal(t, SourceFile, messages["databse"].Source)
+	assert.Equal(t, `unknown config file key "server.timout" in `+configPath+`, did you mean server.timeout?`, messages["server.timout"].Message)
+
+	assert.Equal(t, "unknown env variable TEST_DB_PROT, did you mean TEST_DB_PORT?", messages["TEST_DB_PROT"].Message)
+	assert.Equal(t, "TEST_DB_PROT", messages["TEST_DB_PROT"].EnvVar)
+	assert.Equal(t, "unknown env variable TEST_COMPLETELY_UNKNOWN", messages["TEST_COMPLETELY_UNKNOWN"].Message)
+}
+
+func TestGenericBuilder_StrictCollections(t *testing.T) {
+	setEnvVars(t, map[string]string{
+		"IDX_UPSTREAMS_0_URL":    "http://a.internal",
+		"IDX_TENANTS_ACME_QUOTA": "1",
+	})
+
+	configPath := writeFile(t, t.TempDir(), "config.json", `{
+		"upstreams": [{"url": "http://b.internal", "wieght": 2}],
+		"tenants": {"acme": {"quota": 1}}
+	}`)
+
+	_, err := New(&IndexedConfig{}).EnvPrefix("IDX_").File(&configPath).Strict(StrictError).Build()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `unknown config file key "upstreams[0].wieght" in `+configPath+`, did you mean upstreams[0].weight?`)
+	assert.NotContains(t, err.Error(), "IDX_")
+}
+
+func TestGenericBuilder_StrictProfileEnv(t *testing.T) {
+	env := MapEnv{"PRF_PROFILE": "dev", "PRF_PROFILE_FILE": "/run/secrets/profile", "PRF_PORT": "8081"}
+
+	cfg, err := New(newTestConfig()).EnvPrefix("PRF_").ProfileEnv("PRF_PROFILE").Env(env).Strict(StrictError).Build()
+	require.NoError(t, err)
+	assert.Equal(t, 8081, cfg.Port)
+}
+
+func TestGenericBuilder_StrictWarn(t *testing.T) {
+	setEnvVars(t, map[string]string{"TEST_PORTT": "1"})
+
+	var buf bytes.Buffer
+	previous := slog.Default()
+	slog.SetDefault(slog.New(slog.NewTextHandler(&buf, nil)))
+	t.Cleanup(func() { slog.SetDefault(previous) })
+
+	_, err := New(newTestConfig()).EnvPrefix("TEST_").Strict(StrictWarn).Build()
+	require.NoError(t, err)
+	assert.Contains(t, buf.String(), "Unknown configuration key")
+	assert.Contains(t, buf.String(), "key=TEST_PORTT")
+	assert.Contains(t, buf.String(), "suggestion=TEST_PORT")
+
+	// Strict mode is off by default
+	_, err = New(newTestConfig()).EnvPrefix("TEST_").Build()
+	require.NoError(t, err)
+}
+
+func TestLevenshtein(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{a: "", b: "abc", want: 3},
+		{a: "port", b: "port", want: 0},
+		{a: "prot", b: "port", want: 2},
+		{a: "timout", b: "timeout", want: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.a+"-"+tt.b, func(t *testing.T) {
+			assert.Equal(t, tt.want, levenshtein(tt.a, tt.b))
+		})
+	}
+}
@@ -0,0 +1,237 @@
+package confbuilder
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// SelfValidator is implemented by configurations and nested structs checking rules that span
+// several fields, e.g. ReadTimeout lower than WriteTimeout. Validate runs after the validate
+// tags, returning a FieldError or a ConfigError attributes the problem to specific fields
+type SelfValidator interface {
+	Validate() error
+}
+
+// builtinValidations are the rules registered on every validator used by the builder
+var builtinValidations = map[string]validator.Func{
+	"dsn":              validateDSN,
+	"duration_between": validateDurationBetween,
+	"hostport":         validateHostPort,
+	"existing_file":    validateExistingFile,
+	"existing_dir":     validateExistingDir,
+}
+
+// newValidator returns a validator holding the built-in rules
+func newValidator() *validator.Validate {
+	v := validator.New()
+	_ = registerValidations(v, builtinValidations) // Built-in tags are always valid
+	return v
+}
+
+// registerValidations registers every rule of validations on v
+func registerValidations(v *validator.Validate, validations map[string]validator.Func) error {
+	for tag, fn := range validations {
+		if err := v.RegisterValidation(tag, fn); err != nil {
+			return fmt.Errorf("failed to register validation %s: %w", tag, err)
+		}
+	}
+	return nil
+}
+
+// Validator sets the validator used by every build, e.g. one holding struct level validations
+// or aliases. The built-in rules and the rules added with RegisterValidation are registered on
+// it, replacing rules of the same name
+func (b *Builder[T]) Validator(v *validator.Validate) *Builder[T] {
+	b.validate = v
+	b.validationErr = registerValidations(v, builtinValidations)
+	if b.validationErr == nil {
+		b.validationErr = registerValidations(v, b.validations)
+	}
+	return b
+}
+
+// RegisterValidation adds the rule tag to the validator, usable in validate tags like the
+// built-in ones. Registration problems are reported by Build
+func (b *Builder[T]) RegisterValidation(tag string, fn validator.Func) *Builder[T] {
+	b.validations[tag] = fn
+	if err := registerValidations(b.validate, map[string]validator.Func{tag: fn}); err != nil && b.validationErr == nil {
+		b.validationErr = err
+	}
+	return b
+}
+
+// ValidationMessage sets the message reported when the rule tag fails, replacing the built-in
+// one, e.g. to translate messages. {field} is replaced by the env variable or path of the field
+// and {param} by the rule parameter
+func (b *Builder[T]) ValidationMessage(tag, message string) *Builder[T] {
+	b.messages[tag] = message
+	return b
+}
+
+// runValidateHooks calls Validate on every SelfValidator found in v, its nested structs and
+// the elements of its collections, reporting the errors with the path of the struct
+func runValidateHooks(v reflect.Value, path string, report func(path string, err error)) {
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return
+		}
+		v = v.Elem()
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		if isLeafType(v.Type()) {
+			return
+		}
+		if v.CanAddr() {
+			if sv, ok := v.Addr().Interface().(SelfValidator); ok {
+				if err := sv.Validate(); err != nil {
+					report(path, err)
+				}
+			}
+		}
+		for i := 0; i < v.NumField(); i++ {
+			if v.Type().Field(i).IsExported() {
+				runValidateHooks(v.Field(i), joinPath(path, v.Type().Field(i).Name), report)
+			}
+		}
+
+	case reflect.Slice:
+		if !isStructType(v.Type().Elem()) {
+			return
+		}
+		for i := 0; i < v.Len(); i++ {
+			runValidateHooks(v.Index(i), path+"["+strconv.Itoa(i)+"]", report)
+		}
+
+	case reflect.Map:
+		if !isStructType(v.Type().Elem()) {
+			return
+		}
+		for _, key := range v.MapKeys() {
+			// Map elements are not addressable, validate a copy
+			elem := reflect.New(v.Type().Elem()).Elem()
+			elem.Set(v.MapIndex(key))
+			runValidateHooks(elem, fmt.Sprintf("%s[%v]", path, key.Interface()), report)
+		}
+	}
+}
+
+// addHookError records the error returned by the Validate method of the struct at path
+func addHookError(cfgErr *ConfigError, err error, path string, p Provenance) {
+	var configErr *ConfigError
+	if errors.As(err, &configErr) {
+		for _, fe := range configErr.Fields {
+			addHookFieldError(cfgErr, fe, path, p)
+		}
+		return
+	}
+
+	var fe FieldError
+	if errors.As(err, &fe) {
+		addHookFieldError(cfgErr, fe, path, p)
+		return
+	}
+
+	message := err.Error()
+	if path != "" {
+		message = path + ": " + message
+	}
+	cfgErr.add(FieldError{Path: path, Source: p[path].Source, Message: message, Err: err})
+}
+
+// addHookFieldError records a field error returned by a Validate method, its path being
+// relative to the struct at path
+func addHookFieldError(cfgErr *ConfigError, fe FieldError, path string, p Provenance) {
+	fe.Path = joinPath(path, fe.Path)
+	origin := p[fe.Path]
+	if fe.Source == "" {
+		fe.Source = origin.Source
+	}
+	if fe.EnvVar == "" && (origin.Source == SourceEnv || origin.Source == SourceEnvFile) {
+		fe.EnvVar = origin.Key
+	}
+	if fe.Message == "" && fe.Err != nil {
+		fe.Message = fe.Err.Error()
+	}
+	cfgErr.add(fe)
+}
+
+// validateDSN accepts URL style DSNs with a scheme, e.g. postgres://user@host/db, and key=value
+// DSNs, e.g. host=localhost dbname=app
+func validateDSN(fl validator.FieldLevel) bool {
+	dsn := strings.TrimSpace(fl.Field().String())
+	if dsn == "" {
+		return false
+	}
+	if strings.Contains(dsn, "://") {
+		u, err := url.Parse(dsn)
+		return err == nil && u.Scheme != "" && (u.Host != "" || u.Path != "")
+	}
+	for _, pair := range strings.Fields(dsn) {
+		if key, _, ok := strings.Cut(pair, "="); !ok || key == "" {
+			return false
+		}
+	}
+	return true
+}
+
+// validateDurationBetween checks that a duration lies within the inclusive bounds of the
+// parameter, e.g. duration_between=1s 1m
+func validateDurationBetween(fl validator.FieldLevel) bool {
+	minimum, maximum, ok := durationBounds(fl.Param())
+	if !ok || fl.Field().Type() != durationType {
+		return false
+	}
+	d := time.Duration(fl.Field().Int())
+	return d >= minimum && d <= maximum
+}
+
+// durationBounds parses the space separated bounds of duration_between
+func durationBounds(param string) (time.Duration, time.Duration, bool) {
+	bounds := strings.Fields(param)
+	if len(bounds) != 2 {
+		return 0, 0, false
+	}
+	minimum, err := time.ParseDuration(bounds[0])
+	if err != nil {
+		return 0, 0, false
+	}
+	maximum, err := time.ParseDuration(bounds[1])
+	if err != nil {
+		return 0, 0, false
+	}
+	return minimum, maximum, true
+}
+
+// validateHostPort accepts host:port addresses with a port between 1 and 65535, the host may be
+// omitted for listen addresses, e.g. :8080
+func validateHostPort(fl validator.FieldLevel) bool {
+	host, port, err := net.SplitHostPort(fl.Field().String())
+	if err != nil || strings.ContainsAny(host, " /") {
+		return false
+	}
+	n, err := strconv.Atoi(port)
+	return err == nil && n >= 1 && n <= 65535
+}
+
+// validateExistingFile checks that the path names an existing regular file
+func validateExistingFile(fl validator.FieldLevel) bool {
+	info, err := os.Stat(fl.Field().String())
+	return err == nil && info.Mode().IsRegular()
+}
+
+// validateExistingDir checks that the path names an existing directory
+func validateExistingDir(fl validator.FieldLevel) bool {
+	info, err := os.Stat(fl.Field().String())
+	return err == nil && info.IsDir()
+}
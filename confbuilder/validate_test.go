@@ -0,0 +1,161 @@
+package confbuilder
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type RulesConfig struct {
+	DSN     string        `env:"DSN" validate:"omitempty,dsn"`
+	Timeout time.Duration `env:"TIMEOUT" validate:"duration_between=1s 1m"`
+	Listen  string        `env:"LISTEN" validate:"omitempty,hostport"`
+	CAFile  string        `env:"CA_FILE" validate:"omitempty,existing_file"`
+	DataDir string        `env:"DATA_DIR" validate:"omitempty,existing_dir"`
+}
+
+func TestGenericBuilder_BuiltinValidations(t *testing.T) {
+	dir := t.TempDir()
+	file := writeFile(t, dir, "ca.pem", "cert")
+
+	tests := []struct {
+		name    string
+		env     MapEnv
+		wantErr string
+	}{
+		{name: "Valid", env: MapEnv{
+			"APP_DSN": "postgres://app@db:5432/app", "APP_TIMEOUT": "30s", "APP_LISTEN": ":8080",
+			"APP_CA_FILE": file, "APP_DATA_DIR": dir,
+		}},
+		{name: "Key value DSN", env: MapEnv{"APP_DSN": "host=localhost dbname=app sslmode=disable", "APP_TIMEOUT": "1s"}},
+		{name: "Invalid DSN", env: MapEnv{"APP_DSN": "localhost", "APP_TIMEOUT": "1s"}, wantErr: "APP_DSN must be a valid DSN"},
+		{name: "Duration out of range", env: MapEnv{"APP_TIMEOUT": "2m"}, wantErr: "APP_TIMEOUT must be between 1s and 1m"},
+		{name: "Invalid host port", env: MapEnv{"APP_TIMEOUT": "1s", "APP_LISTEN": "localhost:99999"}, wantErr: "APP_LISTEN must be a host:port address"},
+		{name: "Missing file", env: MapEnv{"APP_TIMEOUT": "1s", "APP_CA_FILE": filepath.Join(dir, "missing.pem")}, wantErr: "APP_CA_FILE must be an existing file"},
+		{name: "File as directory", env: MapEnv{"APP_TIMEOUT": "1s", "APP_DATA_DIR": file}, wantErr: "APP_DATA_DIR must be an existing directory"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New(&RulesConfig{}).EnvPrefix("APP_").Env(tt.env).Build()
+			if tt.wantErr == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
+
+type CustomRuleConfig struct {
+	Region string `env:"REGION" validate:"region"`
+}
+
+func TestGenericBuilder_RegisterValidation(t *testing.T) {
+	region := func(fl validator.FieldLevel) bool {
+		return strings.HasPrefix(fl.Field().String(), "eu-")
+	}
+
+	t.Run("Custom rule and message", func(t *testing.T) {
+		b := New(&CustomRuleConfig{}).
+			EnvPrefix("APP_").
+			RegisterValidation("region", region).
+			ValidationMessage("region", "{field} doit être une région européenne")
+
+		_, err := b.Env(MapEnv{"APP_REGION": "eu-west-1"}).Build()
+		require.NoError(t, err)
+
+		_, err = b.Env(MapEnv{"APP_REGION": "us-east-1"}).Build()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "APP_REGION doit être une région européenne")
+	})
+
+	t.Run("Shared validator", func(t *testing.T) {
+		v := validator.New()
+		b := New(&CustomRuleConfig{}).EnvPrefix("APP_").RegisterValidation("region", region).Validator(v)
+
+		_, err := b.Env(MapEnv{"APP_REGION": "us-east-1"}).Build()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "APP_REGION must satisfy region")
+		assert.NoError(t, v.Var("eu-north-1", "region"), "rules are registered on the provided validator")
+		assert.NoError(t, v.Var(":8080", "hostport"), "built-in rules are registered on the provided validator")
+	})
+
+	t.Run("Invalid tag", func(t *testing.T) {
+		_, err := New(&CustomRuleConfig{}).RegisterValidation("", region).Build()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to register validation")
+	})
+}
+
+type HookConfig struct {
+	ReadTimeout  time.Duration `env:"READ_TIMEOUT"`
+	WriteTimeout time.Duration `env:"WRITE_TIMEOUT"`
+	Pool         HookPool      `env:"POOL"`
+	Replicas     []HookPool    `env:"REPLICAS"`
+}
+
+func (c *HookConfig) Validate() error {
+	if c.ReadTimeout >= c.WriteTimeout {
+		return FieldError{Path: "ReadTimeout", Message: "READ_TIMEOUT must be lower than WRITE_TIMEOUT"}
+	}
+	return nil
+}
+
+type HookPool struct {
+	MinConns int `env:"MIN_CONNS"`
+	MaxConns int `env:"MAX_CONNS"`
+}
+
+func (p HookPool) Validate() error {
+	if p.MinConns > p.MaxConns {
+		return errors.New("min conns exceed max conns")
+	}
+	return nil
+}
+
+func TestGenericBuilder_ValidateHook(t *testing.T) {
+	tests := []struct {
+		name   string
+		env    MapEnv
+		fields []FieldError
+	}{
+		{
+			name: "Valid",
+			env:  MapEnv{"APP_READ_TIMEOUT": "5s", "APP_WRITE_TIMEOUT": "10s"},
+		},
+		{
+			name: "Cross field errors",
+			env: MapEnv{
+				"APP_READ_TIMEOUT": "10s", "APP_WRITE_TIMEOUT": "5s",
+				"APP_POOL_MIN_CONNS": "5", "APP_REPLICAS_0_MIN_CONNS": "2",
+			},
+			fields: []FieldError{
+				{Path: "ReadTimeout", EnvVar: "APP_READ_TIMEOUT", Source: SourceEnv, Message: "READ_TIMEOUT must be lower than WRITE_TIMEOUT"},
+				{Path: "Pool", Message: "Pool: min conns exceed max conns", Err: errors.New("min conns exceed max conns")},
+				{Path: "Replicas[0]", Message: "Replicas[0]: min conns exceed max conns", Err: errors.New("min conns exceed max conns")},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New(&HookConfig{}).EnvPrefix("APP_").Env(tt.env).Build()
+			if tt.fields == nil {
+				require.NoError(t, err)
+				return
+			}
+
+			var cfgErr *ConfigError
+			require.ErrorAs(t, err, &cfgErr)
+			assert.Equal(t, tt.fields, cfgErr.Fields)
+		})
+	}
+}
@@ -0,0 +1,230 @@
+package confbuilder
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// watchDebounce groups the bursts of events editors and orchestrators emit for a single change
+const watchDebounce = 100 * time.Millisecond
+
+// Update is published to subscribers after a reload produced a new valid configuration
+type Update[T any] struct {
+	Config   T
+	Previous T
+	Changes  []Change
+	Report   *Report
+}
+
+// Watcher rebuilds the configuration whenever the configuration or .env files change
+type Watcher[T any] struct {
+	builder *Builder[T]
+	fsw     *fsnotify.Watcher
+	names   map[string]bool // Base names of the watched files
+
+	reloadMu sync.Mutex // Serialises reloads
+
+	store *Store[T]
+	mu    sync.Mutex
+	subs  []func(Update[T])
+
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// Watch builds the configuration and then runs the full pipeline again whenever one of the
+// configuration files or .env files changes, until ctx is done or the watcher is closed.
+// Reloads failing to parse or validate are rejected and the last good configuration is kept
+func (b *Builder[T]) Watch(ctx context.Context) (*Watcher[T], error) {
+	cfg, report, err := b.BuildWithReport()
+	if err != nil {
+		return nil, err
+	}
+
+	fsw, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create file watcher: %w", err)
+	}
+
+	w := &Watcher[T]{
+		builder: b,
+		fsw:     fsw,
+		names:   map[string]bool{},
+		store:   NewStore(cfg),
+		done:    make(chan struct{}),
+	}
+	if err := w.watchPaths(report); err != nil {
+		fsw.Close()
+		return nil, err
+	}
+
+	go w.run(ctx)
+	return w, nil
+}
+
+// Current returns the last valid configuration
+func (w *Watcher[T]) Current() T {
+	return w.store.Load()
+}
+
+// Store returns the store holding the last valid configuration, swapped on every reload
+func (w *Watcher[T]) Store() *Store[T] {
+	return w.store
+}
+
+// Subscribe registers fn to be called, in registration order, after every successful reload
+func (w *Watcher[T]) Subscribe(fn func(Update[T])) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.subs = append(w.subs, fn)
+}
+
+// Reload rebuilds the configuration immediately, e.g. on SIGHUP, and publishes it when it changed
+func (w *Watcher[T]) Reload() error {
+	w.reloadMu.Lock()
+	defer w.reloadMu.Unlock()
+
+	cfg, report, err := w.builder.BuildWithReport()
+	if err != nil {
+		slog.Error("Rejected configuration reload", "error", err)
+		return err
+	}
+
+	previous := w.Current()
+	changes, err := diffConfigs(configTarget(&previous), configTarget(&cfg), w.builder.naming())
+	if err != nil {
+		return err
+	}
+	if len(changes) == 0 {
+		return nil
+	}
+
+	w.store.Swap(cfg)
+
+	w.mu.Lock()
+	subs := append([]func(Update[T]){}, w.subs...)
+	w.mu.Unlock()
+
+	slog.Info("Configuration reloaded", "changes", len(changes))
+	if RequiresRestart(changes) {
+		slog.Warn("Configuration changes require a restart to apply", "changes", restartPaths(changes))
+	}
+	update := Update[T]{Config: cfg, Previous: previous, Changes: changes, Report: report}
+	for _, fn := range subs {
+		fn(update)
+	}
+	return nil
+}
+
+// Close stops watching the files
+func (w *Watcher[T]) Close() error {
+	var err error
+	w.closeOnce.Do(func() {
+		close(w.done)
+		err = w.fsw.Close()
+	})
+	return err
+}
+
+// watchPaths watches the directories holding the files the sources read, watching directories
+// rather than files keeps working when files are replaced by atomic renames
+func (w *Watcher[T]) watchPaths(report *Report) error {
+	dirs := map[string]bool{}
+	for _, file := range report.watchFiles {
+		abs, err := filepath.Abs(file)
+		if err != nil {
+			return err
+		}
+		w.names[filepath.Base(abs)] = true
+		dirs[filepath.Dir(abs)] = true
+	}
+
+	for dir := range dirs {
+		// Optional layers may live in directories that do not exist, builds skip them too
+		if _, err := os.Stat(dir); errors.Is(err, fs.ErrNotExist) {
+			slog.Info("Skipping missing config directory", "dir", dir)
+			continue
+		}
+		if err := w.fsw.Add(dir); err != nil {
+			return fmt.Errorf("failed to watch %s: %w", dir, err)
+		}
+	}
+	return nil
+}
+
+// relevant reports whether the event concerns a watched file, Kubernetes mounted volumes
+// swap their content through hidden ..data entries
+func (w *Watcher[T]) relevant(event fsnotify.Event) bool {
+	if event.Op == fsnotify.Chmod {
+		return false
+	}
+	name := filepath.Base(event.Name)
+	return w.names[name] || strings.HasPrefix(name, "..")
+}
+
+// run dispatches file events until the watcher is closed
+func (w *Watcher[T]) run(ctx context.Context) {
+	var timer *time.Timer
+	var reload <-chan time.Time
+
+	for {
+		select {
+		case <-ctx.Done():
+			w.Close()
+			return
+		case <-w.done:
+			return
+		case event, ok := <-w.fsw.Events:
+			if !ok {
+				return
+			}
+			if !w.relevant(event) {
+				continue
+			}
+			if timer == nil {
+				timer = time.NewTimer(watchDebounce)
+			} else {
+				timer.Reset(watchDebounce)
+			}
+			reload = timer.C
+		case err, ok := <-w.fsw.Errors:
+			if !ok {
+				return
+			}
+			slog.Warn("Configuration watcher error", "error", err)
+		case <-reload:
+			reload = nil
+			w.Reload() // Failures are logged and the last good configuration is kept
+		}
+	}
+}
+
+// restartPaths returns the paths of the changes requiring a restart
+func restartPaths(changes []Change) []string {
+	var paths []string
+	for _, c := range changes {
+		if c.Restart {
+			paths = append(paths, c.Path)
+		}
+	}
+	return paths
+}
+
+// configTarget returns a pointer to the configuration usable as a walk or decode target
+func configTarget[T any](cfg *T) any {
+	if reflect.ValueOf(*cfg).Kind() == reflect.Ptr {
+		return *cfg
+	}
+	return cfg
+}
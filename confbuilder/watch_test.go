@@ -0,0 +1,131 @@
+package confbuilder
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenericBuilder_Watch(t *testing.T) {
+	dir := t.TempDir()
+	configPath := writeFile(t, dir, "config.json", `{"port": 8081, "log_level": "INFO"}`)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	w, err := New(newTestConfig()).File(&configPath).Watch(ctx)
+	require.NoError(t, err)
+	defer w.Close()
+	assert.Equal(t, 8081, w.Current().Port)
+
+	updates := make(chan Update[*TestConfig], 1)
+	w.Subscribe(func(u Update[*TestConfig]) { updates <- u })
+
+	writeFile(t, dir, "config.json", `{"port": 8082, "log_level": "DEBUG"}`)
+
+	select {
+	case u := <-updates:
+		assert.Equal(t, 8082, u.Config.Port)
+		assert.Equal(t, 8081, u.Previous.Port)
+		assert.Equal(t, []Change{
+			{Path: "Port", Old: 8081, New: 8082},
+			{Path: "LogLevel", Old: slog.LevelInfo, New: slog.LevelDebug},
+		}, u.Changes)
+		assert.Equal(t, Origin{Source: SourceFile, File: configPath}, u.Report.Provenance["Port"])
+	case <-time.After(5 * time.Second):
+		t.Fatal("no update received after the config file changed")
+	}
+	assert.Equal(t, 8082, w.Current().Port)
+}
+
+func TestWatcher_RejectsInvalidReload(t *testing.T) {
+	dir := t.TempDir()
+	configPath := writeFile(t, dir, "config.json", `{"port": 8081}`)
+
+	w, err := New(newTestConfig()).File(&configPath).Watch(context.Background())
+	require.NoError(t, err)
+	defer w.Close()
+
+	called := false
+	w.Subscribe(func(Update[*TestConfig]) { called = true })
+
+	writeFile(t, dir, "config.json", `{"port": 70000}`)
+	err = w.Reload()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid configuration")
+	assert.Equal(t, 8081, w.Current().Port) // Last good configuration kept
+
+	writeFile(t, dir, "config.json", `{"port": `)
+	require.Error(t, w.Reload())
+	assert.Equal(t, 8081, w.Current().Port)
+	assert.False(t, called)
+
+	// Unchanged configurations are not published
+	writeFile(t, dir, "config.json", `{"port": 8081}`)
+	require.NoError(t, w.Reload())
+	assert.False(t, called)
+}
+
+func TestWatcher_ReloadsEnvFiles(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, ".env.watch", "TEST_PORT=7071\n")
+	t.Cleanup(func() { os.Unsetenv("TEST_PORT") })
+
+	originalWD, err := os.Getwd()
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(dir))
+	t.Cleanup(func() { os.Chdir(originalWD) })
+
+	w, err := New(newTestConfig()).EnvPrefix("TEST_").EnvFiles(".env.watch").Watch(context.Background())
+	require.NoError(t, err)
+	defer w.Close()
+	assert.Equal(t, 7071, w.Current().Port)
+
+	writeFile(t, dir, ".env.watch", "TEST_PORT=7072\n")
+	require.NoError(t, w.Reload())
+	assert.Equal(t, 7072, w.Current().Port)
+}
+
+func TestWatcher_MissingOptionalDirectory(t *testing.T) {
+	dir := t.TempDir()
+	configPath := writeFile(t, dir, "config.json", `{"port": 8081}`)
+
+	w, err := New(newTestConfig()).Files(RequiredFile(configPath), OptionalFile(filepath.Join(dir, "nope", "local.json"))).Watch(context.Background())
+	require.NoError(t, err)
+	defer w.Close()
+	assert.Equal(t, 8081, w.Current().Port)
+}
+
+func TestWatcher_InitialBuildError(t *testing.T) {
+	defaultCfg := newTestConfig()
+	defaultCfg.AppName = ""
+
+	_, err := New(defaultCfg).Watch(context.Background())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid configuration")
+}
+
+func TestWatcher_StopsWithContext(t *testing.T) {
+	configPath := writeFile(t, t.TempDir(), "config.json", `{}`)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	w, err := New(newTestConfig()).File(&configPath).Watch(ctx)
+	require.NoError(t, err)
+
+	cancel()
+	require.Eventually(t, func() bool {
+		select {
+		case <-w.done:
+			return true
+		default:
+			return false
+		}
+	}, time.Second, 10*time.Millisecond)
+	assert.NoError(t, w.Close())
+}
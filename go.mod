@@ -3,6 +3,8 @@ module github.com/fulcrumproject/utils
 go 1.24
 
 require (
+	github.com/BurntSushi/toml v1.5.0
+	github.com/fsnotify/fsnotify v1.9.0
 	github.com/go-playground/validator/v10 v10.26.0
 	github.com/jinzhu/copier v0.4.0
 	github.com/stretchr/testify v1.10.0
@@ -35,6 +37,6 @@ require (
 	github.com/joho/godotenv v1.5.1
 	github.com/orandin/slog-gorm v1.4.0
 	github.com/pmezard/go-difflib v1.0.0 // indirect
-	gopkg.in/yaml.v3 v3.0.1 // indirect
+	gopkg.in/yaml.v3 v3.0.1
 	gorm.io/driver/postgres v1.6.0
 )
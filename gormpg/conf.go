@@ -6,7 +6,7 @@ import (
 
 // Fulcrum Conf configuration
 type Conf struct {
-	DSN       string     `json:"dsn" env:"DSN" validate:"required"`
+	DSN       string     `json:"dsn" env:"DSN" validate:"required" secret:"true"`
 	LogLevel  slog.Level `json:"logLevel" env:"LOG_LEVEL"`
 	LogFormat string     `json:"logFormat" env:"LOG_FORMAT" validate:"omitempty,oneof=text json"`
 }